@@ -1,49 +1,113 @@
 package main
 
 import (
+	"os"
+
 	handler "github.com/mariobassem/sminit-go/internal/handlers"
+	"github.com/mariobassem/sminit-go/internal/manager"
 	"github.com/spf13/cobra"
 )
 
 func main() {
+	manager.RunExecHelper()
+
 	var rootCmd = &cobra.Command{
 		Use:       "sminit [subcommand]",
 		Short:     "sminit is a trivial service manager",
 		Example:   "sminit start service_name",
-		ValidArgs: []string{"init", "start", "stop", "add", "delete", "list"},
+		ValidArgs: []string{"init", "start", "stop", "enable", "disable", "add", "delete", "list", "status", "restart", "kill", "reload-service", "events", "history", "reexec"},
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ValidateOutput()
+		},
+		SilenceUsage:  true,
+		SilenceErrors: true,
 	}
+	rootCmd.PersistentFlags().StringVarP(&handler.Output, "output", "o", handler.OutputTable, "output format, one of table, json, yaml")
 
+	var initLogFormat string
+	var initLogLevel string
+	var initSyslog bool
+	var initOptions manager.AppOptions
 	var initCmd = &cobra.Command{
 		Use: "init",
-		Run: func(cmd *cobra.Command, args []string) {
-			handler.InitHandler()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.InitHandler(initLogFormat, initLogLevel, initSyslog, initOptions)
 		},
 		Short: "Start a process that starts and watches all services defined in /etc/sminit",
 		Args:  cobra.ExactArgs(0),
 	}
+	initCmd.Flags().StringVar(&initLogFormat, "log-format", "console", "format of sminit logs, one of console, json")
+	initCmd.Flags().StringVar(&initLogLevel, "log-level", "info", "minimum level of sminit logs, one of trace, debug, info, warn, error")
+	initCmd.Flags().BoolVar(&initSyslog, "syslog", false, "copy sminit logs to the local syslog at /dev/log, service log lines are tagged with the service name")
+	initCmd.Flags().StringVar(&initOptions.StateDir, "state-dir", manager.DefaultStateDir, "directory sminit keeps its state in, like the journal of service events")
+	initCmd.Flags().BoolVar(&initOptions.Metrics, "metrics", false, "expose service and api metrics in prometheus text format at /metrics")
+	initCmd.Flags().StringVar(&initOptions.CgroupRoot, "cgroup-root", manager.DefaultCgroupRoot, "cgroup v2 directory the cgroups of services are created in")
 
 	var startCmd = &cobra.Command{
 		Use: "start",
-		Run: func(cmd *cobra.Command, args []string) {
-			handler.StartHandler(args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.StartHandler(args)
 		},
 		Short: "Start a service that is already watched by sminit",
 		Args:  cobra.ExactArgs(1),
 	}
 
+	var enableCmd = &cobra.Command{
+		Use: "enable",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.EnableHandler(args)
+		},
+		Short: "Allow a disabled service to be started, and start it if its dependencies are running",
+		Args:  cobra.ExactArgs(1),
+	}
+
+	var disableCmd = &cobra.Command{
+		Use: "disable",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.DisableHandler(args)
+		},
+		Short: "Stop a service, and prevent it from being started until it is enabled",
+		Args:  cobra.ExactArgs(1),
+	}
+
+	var reexecCmd = &cobra.Command{
+		Use: "reexec",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ReexecHandler()
+		},
+		Short: "Replace the running sminit daemon with the sminit binary at its path, keeping services running",
+		Args:  cobra.ExactArgs(0),
+	}
+
 	var listCmd = &cobra.Command{
 		Use: "list",
-		Run: func(cmd *cobra.Command, args []string) {
-			handler.ListHandler()
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ListHandler()
 		},
 		Short: "List all services that are watched by sminit",
 		Args:  cobra.ExactArgs(0),
 	}
 
+	var statusLines int
+	var statusJSON bool
+	var statusCmd = &cobra.Command{
+		Use: "status [service_name]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if statusJSON {
+				handler.Output = handler.OutputJSON
+			}
+			return handler.StatusHandler(args, statusLines)
+		},
+		Short: "Show the definition, status, process, dependencies and last log lines of a service",
+		Args:  cobra.ExactArgs(1),
+	}
+	statusCmd.Flags().IntVarP(&statusLines, "lines", "n", 10, "number of log lines to show")
+	statusCmd.Flags().BoolVar(&statusJSON, "json", false, "print service details as json, same as --output json")
+
 	var addCmd = &cobra.Command{
 		Use: "add [service_name]",
-		Run: func(cmd *cobra.Command, args []string) {
-			handler.AddHandler(args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.AddHandler(args)
 		},
 		Short: "Add a new service that has a definition file in /etc/sminit to the services watched by sminit",
 		Args:  cobra.ExactArgs(1),
@@ -51,8 +115,8 @@ func main() {
 
 	var deleteCmd = &cobra.Command{
 		Use: "delete [service_name]",
-		Run: func(cmd *cobra.Command, args []string) {
-			handler.DeleteHandler(args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.DeleteHandler(args)
 		},
 		Short: "Drop a service from the list of services that are being watched by sminit",
 		Args:  cobra.ExactArgs(1),
@@ -60,28 +124,109 @@ func main() {
 
 	var stopCmd = &cobra.Command{
 		Use: "stop [service_name]",
-		Run: func(cmd *cobra.Command, args []string) {
-			handler.StopHandler(args)
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.StopHandler(args)
 		},
 		Short: "Stop a running service",
 		Args:  cobra.ExactArgs(1),
 	}
 
+	var restartWithDependents bool
+	var restartCmd = &cobra.Command{
+		Use: "restart [service_name]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.RestartHandler(args, restartWithDependents)
+		},
+		Short: "Stop a service gracefully, then start it again",
+		Args:  cobra.ExactArgs(1),
+	}
+	restartCmd.Flags().BoolVar(&restartWithDependents, "with-dependents", false, "also restart services that depend on this service once it is healthy again")
+
+	var killSignal string
+	var killGroup bool
+	var killCmd = &cobra.Command{
+		Use: "kill [service_name]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.KillHandler(args, killSignal, killGroup)
+		},
+		Short:   "Send a signal to the process of a service without changing its status",
+		Example: "sminit kill -s SIGHUP service_name",
+		Args:    cobra.ExactArgs(1),
+	}
+	killCmd.Flags().StringVarP(&killSignal, "signal", "s", "SIGTERM", "signal to send, as a name like SIGHUP or HUP, or a number")
+	killCmd.Flags().BoolVar(&killGroup, "group", false, "send the signal to the whole process group of the service")
+
+	var reloadCmd = &cobra.Command{
+		Use: "reload-service [service_name]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.ReloadHandler(args)
+		},
+		Short: "Make a running service re-read its configuration using the reload command or signal in its definition",
+		Args:  cobra.ExactArgs(1),
+	}
+
+	var logFollow bool
+	var logLines int
+	var logSince string
 	var logCmd = &cobra.Command{
-		Use: "log",
-		Run: func(cmd *cobra.Command, args []string) {
-			handler.LogHandler()
+		Use: "log [service_name]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// sminit logs are followed unless --follow=false is given
+			if len(args) == 0 && !cmd.Flags().Changed("follow") {
+				logFollow = true
+			}
+			return handler.LogHandler(args, logFollow, logLines, logSince)
 		},
-		Short: "Show sminit logs",
-		Args:  cobra.ExactArgs(0),
+		Short: "Show the recent logs of a service, or sminit logs if no service is given",
+		Args:  cobra.MaximumNArgs(1),
 	}
+	logCmd.Flags().BoolVarP(&logFollow, "follow", "f", false, "keep printing new log lines, sminit logs are followed by default")
+	logCmd.Flags().IntVarP(&logLines, "lines", "n", -1, "number of recent lines to show, all lines are shown if negative")
+	logCmd.Flags().StringVar(&logSince, "since", "", "only show service lines written after this RFC3339 time, or this duration ago like 10m")
+
+	var eventsFollow bool
+	var eventsCmd = &cobra.Command{
+		Use: "events [service_name]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.EventsHandler(args, eventsFollow)
+		},
+		Short: "Show recent status changes, additions, deletions, reloads and health checks of a service, or of all services if no service is given",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	eventsCmd.Flags().BoolVarP(&eventsFollow, "follow", "f", false, "keep printing new events")
+
+	var historySince string
+	var historyStateDir string
+	var historyCmd = &cobra.Command{
+		Use: "history [service_name]",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return handler.HistoryHandler(args, historySince, historyStateDir)
+		},
+		Short: "Show the status changes and exits of a service, or of all services if no service is given, kept on disk across sminit restarts",
+		Args:  cobra.MaximumNArgs(1),
+	}
+	historyCmd.Flags().StringVar(&historySince, "since", "", "only show events after this RFC3339 time, or this duration ago like 1h")
+	historyCmd.Flags().StringVar(&historyStateDir, "state-dir", manager.DefaultStateDir, "directory sminit keeps its state in")
 
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(addCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(stopCmd)
+	rootCmd.AddCommand(enableCmd)
+	rootCmd.AddCommand(disableCmd)
+	rootCmd.AddCommand(restartCmd)
+	rootCmd.AddCommand(killCmd)
+	rootCmd.AddCommand(reloadCmd)
 	rootCmd.AddCommand(listCmd)
+	rootCmd.AddCommand(statusCmd)
 	rootCmd.AddCommand(logCmd)
-	_ = rootCmd.Execute()
+	rootCmd.AddCommand(eventsCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(reexecCmd)
+
+	if err := rootCmd.Execute(); err != nil {
+		handler.PrintError(err)
+		os.Exit(1)
+	}
 }
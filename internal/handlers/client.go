@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/mariobassem/sminit-go/internal/manager"
+	"github.com/pkg/errors"
+)
+
+// sendRequest sends a request to the running sminit instance, and returns the body of the response if the request succeeded.
+func sendRequest(method, path string, body io.Reader) ([]byte, error) {
+	request, err := http.NewRequest(method, fmt.Sprintf("http://%s:%d%s", manager.Address, manager.Port, path), body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating request")
+	}
+
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "error sending request")
+	}
+	defer response.Body.Close()
+
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading sminit response body")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, responseError(response, responseBody)
+	}
+
+	return responseBody, nil
+}
+
+// openStream sends a GET request to the running sminit instance, and returns the body of the response to be read as a stream.
+// the caller should close the returned body.
+func openStream(path string) (io.ReadCloser, error) {
+	response, err := http.Get(fmt.Sprintf("http://%s:%d%s", manager.Address, manager.Port, path))
+	if err != nil {
+		return nil, errors.Wrap(err, "error sending request")
+	}
+
+	if response.StatusCode != http.StatusOK {
+		defer response.Body.Close()
+		body, err := io.ReadAll(response.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading sminit response body")
+		}
+		return nil, responseError(response, body)
+	}
+
+	return response.Body, nil
+}
+
+// responseError converts the body of a failed request to an error
+func responseError(response *http.Response, body []byte) error {
+	errResponse := manager.ErrorResponse{}
+	if err := json.Unmarshal(body, &errResponse); err != nil || errResponse.Message == "" {
+		return fmt.Errorf("%s: %s", response.Status, string(body))
+	}
+	return fmt.Errorf("%s: %s", errResponse.Code, errResponse.Message)
+}
@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+func DisableHandler(args []string) error {
+	_, err := sendRequest(http.MethodPut, fmt.Sprintf("/services/%s/disable", args[0]), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to disable service %s", args[0])
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/mariobassem/sminit-go/internal/manager"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// EventsHandler prints the recent events of the service with the given name, or of all services if no name is given,
+// then keeps printing new events if follow is true.
+func EventsHandler(args []string, follow bool) error {
+	query := url.Values{}
+	query.Set("follow", fmt.Sprint(follow))
+	if len(args) > 0 {
+		query.Set("service", args[0])
+	}
+
+	body, err := openStream(fmt.Sprintf("/events?%s", query.Encode()))
+	if err != nil {
+		return errors.Wrap(err, "failed to get events")
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		event := manager.Event{}
+		err := json.Unmarshal(scanner.Bytes(), &event)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal event")
+		}
+
+		err = printEvent(event)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func printEvent(event manager.Event) error {
+	switch Output {
+	case OutputJSON:
+		out, err := json.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal event to json")
+		}
+		fmt.Println(string(out))
+
+	case OutputYAML:
+		out, err := yaml.Marshal(event)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal event to yaml")
+		}
+		fmt.Printf("---\n%s", string(out))
+
+	default:
+		fmt.Printf("%s %s %s %s\n", event.Time.Format(time.RFC3339), event.Service, event.Type, formatEvent(event))
+	}
+	return nil
+}
+
+// formatEvent describes what happened in an event
+func formatEvent(event manager.Event) string {
+	details := []string{}
+	switch event.Type {
+	case manager.EventStatus:
+		details = append(details, fmt.Sprintf("%s -> %s", event.OldStatus, event.NewStatus))
+		if event.Reason != "" {
+			details = append(details, fmt.Sprintf("reason=%s", event.Reason))
+		}
+		if event.Error != "" {
+			details = append(details, fmt.Sprintf("condition=%q", event.Error))
+		}
+	case manager.EventHealth:
+		details = append(details, fmt.Sprintf("healthy=%t", event.Healthy))
+	case manager.EventExit:
+		if event.Exit != nil && event.Exit.Signal != "" {
+			details = append(details, fmt.Sprintf("signal=%s", event.Exit.Signal))
+		} else if event.Exit != nil {
+			details = append(details, fmt.Sprintf("code=%d", event.Exit.Code))
+		}
+	case manager.EventReload:
+		if event.Error != "" {
+			details = append(details, fmt.Sprintf("error=%q", event.Error))
+		}
+	}
+	if event.PID != 0 {
+		details = append(details, fmt.Sprintf("pid=%d", event.PID))
+	}
+	return strings.Join(details, " ")
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/mariobassem/sminit-go/internal/manager"
+)
+
+// HistoryHandler prints the events kept in the journal in stateDir for the service with the given name,
+// or for all services if no name is given. the journal is read directly, so it works while sminit is not running.
+func HistoryHandler(args []string, since string, stateDir string) error {
+	name := ""
+	if len(args) > 0 {
+		name = args[0]
+	}
+
+	sinceTime := time.Time{}
+	if since != "" {
+		t, err := manager.ParseSince(since)
+		if err != nil {
+			return err
+		}
+		sinceTime = t
+	}
+
+	events, err := manager.ReadJournal(stateDir, name, sinceTime)
+	if err != nil {
+		return err
+	}
+
+	for _, event := range events {
+		if err := printEvent(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -1,13 +1,23 @@
 package handler
 
 import (
-	"log"
-
 	"github.com/mariobassem/sminit-go/internal/manager"
+	"github.com/pkg/errors"
 	"github.com/sevlyar/go-daemon"
 )
 
-func InitHandler() {
+func InitHandler(logFormat string, logLevel string, toSyslog bool, opts manager.AppOptions) error {
+	err := manager.ConfigureLog(logFormat, logLevel, toSyslog)
+	if err != nil {
+		return err
+	}
+
+	// an instance executed by sminit reexec replaces the running daemon, it is already daemonized
+	if manager.IsReexec() {
+		defer manager.CleanUp()
+		return startApp(opts)
+	}
+
 	ctx := &daemon.Context{
 		LogFilePerm: 0640,
 		WorkDir:     "/",
@@ -17,18 +27,24 @@ func InitHandler() {
 
 	d, err := ctx.Reborn()
 	if err != nil {
-		log.Fatal("Unable to run: ", err)
+		return errors.Wrap(err, "unable to run")
 	}
 	if d != nil {
-		return
+		return nil
 	}
 	defer func() {
 		_ = ctx.Release()
 	}()
+	defer manager.CleanUp()
 
-	err = manager.StartApp()
+	return startApp(opts)
+}
+
+func startApp(opts manager.AppOptions) error {
+	err := manager.StartApp(opts)
 	if err != nil {
 		manager.SminitLog.Error().Msg(err.Error())
+		return err
 	}
-	defer manager.CleanUp()
+	return nil
 }
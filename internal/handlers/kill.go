@@ -0,0 +1,17 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/pkg/errors"
+)
+
+func KillHandler(args []string, signal string, group bool) error {
+	_, err := sendRequest(http.MethodPut, fmt.Sprintf("/services/%s/signal?signal=%s&group=%t", args[0], url.QueryEscape(signal), group), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to send %s to service %s", signal, args[0])
+	}
+	return nil
+}
@@ -4,38 +4,56 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
+	"sort"
+	"time"
 
 	"github.com/mariobassem/sminit-go/internal/manager"
+	"github.com/pkg/errors"
 )
 
-func ListHandler() {
-	response, err := http.Get(fmt.Sprintf("http://%s:%d/services", manager.Address, manager.Port))
+func ListHandler() error {
+	body, err := sendRequest(http.MethodGet, "/services", nil)
 	if err != nil {
-		manager.SminitLog.Error().Msgf("error sending list request: %s", err.Error())
-		return
+		return errors.Wrap(err, "failed to list services")
 	}
 
-	if response.StatusCode != http.StatusOK {
-		manager.SminitLog.Error().Msgf("failure: %s", response.Status)
-		return
+	services := []manager.ServiceDesc{}
+	err = json.Unmarshal(body, &services)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal message content")
 	}
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
 
-	body, err := io.ReadAll(response.Body)
-	if err != nil {
-		manager.SminitLog.Error().Msgf("error reading sminit response body: %s", err.Error())
-		return
+	return printResult(services, func(w io.Writer) {
+		fmt.Fprintln(w, "NAME\tSTATUS\tPID\tRESTARTS\tNEXT RUN\tLAST RUN\tLINKED TO")
+		for _, service := range services {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n", service.Name, service.Status, formatPID(service.PID), service.Restarts, formatRunTime(service.NextRun), formatRunTime(service.LastRun), formatLinkedTo(service.LinkedTo))
+		}
+	})
+}
+
+// formatRunTime formats the next or last run time of a scheduled service, it returns - for services without a run time
+func formatRunTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
 	}
-	services := []manager.Service{}
-	err = json.Unmarshal(body, &services)
-	if err != nil {
-		manager.SminitLog.Error().Msgf("failed to unmarshal message content. %s", err.Error())
-		return
+	return t.Format(time.RFC3339)
+}
+
+// formatLinkedTo formats the service a log companion receives output from, it returns - for services
+func formatLinkedTo(name string) string {
+	if name == "" {
+		return "-"
 	}
-	manager.SminitLog.Info().Msg("tracked services:")
-	for idx := range services {
-		// TODO: needs to be changed
-		_, _ = log.Default().Writer().Write([]byte(fmt.Sprintf("\tname: %s, status: %s\n", services[idx].Name, services[idx].Status)))
+	return name
+}
+
+func formatPID(pid int) string {
+	if pid == 0 {
+		return "-"
 	}
+	return fmt.Sprint(pid)
 }
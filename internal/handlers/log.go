@@ -1,19 +1,116 @@
 package handler
 
 import (
+	"bufio"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"time"
 
 	"github.com/mariobassem/sminit-go/internal/manager"
 	"github.com/nxadm/tail"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
 )
 
-func LogHandler() {
-	t, err := tail.TailFile(manager.SminitLogPath, tail.Config{Follow: true})
+// LogHandler prints the logs of the service with the given name, or sminit logs if no name is given.
+// at most lines lines are printed before following, all lines are printed if lines is negative.
+func LogHandler(args []string, follow bool, lines int, since string) error {
+	if len(args) == 0 {
+		return printDaemonLogs(follow, lines)
+	}
+
+	query := url.Values{}
+	query.Set("follow", fmt.Sprint(follow))
+	query.Set("tail", fmt.Sprint(lines))
+	if since != "" {
+		query.Set("since", since)
+	}
+
+	body, err := openStream(fmt.Sprintf("/services/%s/logs?%s", args[0], query.Encode()))
+	if err != nil {
+		return errors.Wrapf(err, "failed to get logs of service %s", args[0])
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	for scanner.Scan() {
+		line := manager.LogLine{}
+		err := json.Unmarshal(scanner.Bytes(), &line)
+		if err != nil {
+			return errors.Wrap(err, "failed to unmarshal log line")
+		}
+
+		err = printLogLine(line)
+		if err != nil {
+			return err
+		}
+	}
+
+	return scanner.Err()
+}
+
+func printLogLine(line manager.LogLine) error {
+	switch Output {
+	case OutputJSON:
+		out, err := json.Marshal(line)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal log line to json")
+		}
+		fmt.Println(string(out))
+
+	case OutputYAML:
+		out, err := yaml.Marshal(line)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal log line to yaml")
+		}
+		fmt.Printf("---\n%s", string(out))
+
+	default:
+		fmt.Printf("%s [%s] %s\n", line.Time.Format(time.RFC3339), line.Stream, line.Line)
+	}
+	return nil
+}
+
+// printDaemonLogs prints the last lines lines of sminit log file, then follows it if follow is true
+func printDaemonLogs(follow bool, lines int) error {
+	file, err := os.Open(manager.SminitLogPath)
+	if err != nil {
+		return errors.Wrap(err, "failed to print logs")
+	}
+	defer file.Close()
+
+	last := []string{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		last = append(last, scanner.Text())
+		if lines >= 0 && len(last) > lines {
+			last = last[1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return errors.Wrap(err, "failed to print logs")
+	}
+	for _, line := range last {
+		fmt.Println(line)
+	}
+
+	if !follow {
+		return nil
+	}
+
+	t, err := tail.TailFile(manager.SminitLogPath, tail.Config{
+		Follow:   true,
+		ReOpen:   true,
+		Location: &tail.SeekInfo{Offset: 0, Whence: io.SeekEnd},
+	})
 	if err != nil {
-		manager.SminitLog.Error().Msgf("failed to print logs. %s", err.Error())
-		return
+		return errors.Wrap(err, "failed to follow logs")
 	}
 	for line := range t.Lines {
 		fmt.Println(line.Text)
 	}
+	return nil
 }
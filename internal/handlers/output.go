@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+)
+
+// Output is the format handlers print their results in
+var Output = OutputTable
+
+// ValidateOutput checks that Output is one of the supported formats
+func ValidateOutput() error {
+	switch Output {
+	case OutputTable, OutputJSON, OutputYAML:
+		return nil
+	}
+	return fmt.Errorf("unsupported output format %s, should be one of table, json, yaml", Output)
+}
+
+// printResult prints v as json or yaml, or calls printTable for table output
+func printResult(v any, printTable func(w io.Writer)) error {
+	switch Output {
+	case OutputJSON:
+		out, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal output to json")
+		}
+		fmt.Println(string(out))
+
+	case OutputYAML:
+		out, err := yaml.Marshal(v)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal output to yaml")
+		}
+		fmt.Print(string(out))
+
+	default:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		printTable(w)
+		return w.Flush()
+	}
+
+	return nil
+}
+
+// PrintError prints err to stderr, as an object with an error field if the output format is json or yaml
+func PrintError(err error) {
+	output := map[string]string{"error": err.Error()}
+	switch Output {
+	case OutputJSON:
+		out, _ := json.Marshal(output)
+		fmt.Fprintln(os.Stderr, string(out))
+
+	case OutputYAML:
+		out, _ := yaml.Marshal(output)
+		fmt.Fprint(os.Stderr, string(out))
+
+	default:
+		fmt.Fprintf(os.Stderr, "error: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,15 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+func ReexecHandler() error {
+	_, err := sendRequest(http.MethodPut, "/reexec", nil)
+	if err != nil {
+		return errors.Wrap(err, "failed to re-execute sminit")
+	}
+	return nil
+}
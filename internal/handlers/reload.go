@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+func ReloadHandler(args []string) error {
+	_, err := sendRequest(http.MethodPut, fmt.Sprintf("/services/%s/reload", args[0]), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to reload service %s", args[0])
+	}
+	return nil
+}
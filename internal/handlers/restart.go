@@ -0,0 +1,16 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+func RestartHandler(args []string, withDependents bool) error {
+	_, err := sendRequest(http.MethodPut, fmt.Sprintf("/services/%s/restart?with_dependents=%t", args[0], withDependents), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to restart service %s", args[0])
+	}
+	return nil
+}
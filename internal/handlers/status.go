@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mariobassem/sminit-go/internal/manager"
+	"github.com/pkg/errors"
+)
+
+func StatusHandler(args []string, lines int) error {
+	body, err := sendRequest(http.MethodGet, fmt.Sprintf("/services/%s?lines=%d", args[0], lines), nil)
+	if err != nil {
+		return errors.Wrapf(err, "failed to get status of service %s", args[0])
+	}
+
+	service := manager.ServiceDetails{}
+	err = json.Unmarshal(body, &service)
+	if err != nil {
+		return errors.Wrap(err, "failed to unmarshal message content")
+	}
+
+	return printResult(service, func(w io.Writer) {
+		fmt.Fprintf(w, "name:\t%s\n", service.Name)
+		fmt.Fprintf(w, "status:\t%s\n", service.Status)
+		if service.PID != 0 {
+			fmt.Fprintf(w, "pid:\t%d\n", service.PID)
+			fmt.Fprintf(w, "started at:\t%s\n", service.StartedAt.Format(time.RFC3339))
+			fmt.Fprintf(w, "uptime:\t%s\n", service.Uptime.Round(time.Second))
+		}
+		fmt.Fprintf(w, "restarts:\t%d\n", service.Restarts)
+		if service.LastExit != nil {
+			fmt.Fprintf(w, "last exit:\t%s\n", formatExit(*service.LastExit))
+		}
+		if service.FailureReason != "" {
+			fmt.Fprintf(w, "failure reason:\t%s\n", service.FailureReason)
+		}
+		if service.SkipReason != "" {
+			fmt.Fprintf(w, "skip reason:\t%s\n", service.SkipReason)
+		}
+		if service.Cgroup != "" {
+			fmt.Fprintf(w, "cgroup:\t%s\n", service.Cgroup)
+		}
+		if service.Resources != nil {
+			fmt.Fprintf(w, "memory:\t%d bytes\n", service.Resources.MemoryCurrent)
+			fmt.Fprintf(w, "cpu:\t%s\n", service.Resources.CPUUsage)
+			fmt.Fprintf(w, "tasks:\t%d\n", service.Resources.Tasks)
+		}
+		fmt.Fprintf(w, "last change:\t%s\n", service.LastChange.Format(time.RFC3339))
+		if service.LastReload != nil {
+			fmt.Fprintf(w, "last reload:\t%s\n", formatReload(*service.LastReload))
+		}
+		if !service.NextRun.IsZero() {
+			fmt.Fprintf(w, "next run:\t%s\n", service.NextRun.Format(time.RFC3339))
+		}
+		if !service.LastRun.IsZero() {
+			fmt.Fprintf(w, "last run:\t%s\n", service.LastRun.Format(time.RFC3339))
+		}
+
+		fmt.Fprintln(w, "definition:")
+		fmt.Fprintf(w, "  cmd:\t%s\n", service.Definition.Cmd)
+		fmt.Fprintf(w, "  log:\t%s\n", service.Definition.Log)
+		fmt.Fprintf(w, "  oneshot:\t%t\n", service.Definition.OneShot)
+		fmt.Fprintf(w, "  healthcheck:\t%s\n", service.Definition.HealthCheck)
+		if service.Definition.Reload != "" {
+			fmt.Fprintf(w, "  reload:\t%s\n", service.Definition.Reload)
+		}
+		if service.Definition.StartDelay != 0 {
+			fmt.Fprintf(w, "  start delay:\t%s\n", service.Definition.StartDelay)
+		}
+		if service.Definition.StartTimeout != 0 {
+			fmt.Fprintf(w, "  start timeout:\t%s\n", service.Definition.StartTimeout)
+		}
+		if service.Definition.StopTimeout != 0 {
+			fmt.Fprintf(w, "  stop timeout:\t%s\n", service.Definition.StopTimeout)
+		}
+		printResources(w, service.Definition.Resources)
+		printTunables(w, service.Definition)
+		printHooks(w, "exec_start_pre", service.Definition.ExecStartPre)
+		printHooks(w, "exec_start_post", service.Definition.ExecStartPost)
+		printHooks(w, "exec_stop", service.Definition.ExecStop)
+		printHooks(w, "exec_stop_post", service.Definition.ExecStopPost)
+		if len(service.Definition.OnFailure) > 0 {
+			fmt.Fprintf(w, "  on failure:\t%s\n", strings.Join(service.Definition.OnFailure, ", "))
+		}
+		if len(service.Definition.OnSuccess) > 0 {
+			fmt.Fprintf(w, "  on success:\t%s\n", strings.Join(service.Definition.OnSuccess, ", "))
+		}
+		if service.Definition.Schedule.Cron != "" {
+			fmt.Fprintf(w, "  schedule:\t%s\n", service.Definition.Schedule.Cron)
+		} else if service.Definition.Schedule.Every != 0 {
+			fmt.Fprintf(w, "  schedule:\tevery %s\n", service.Definition.Schedule.Every)
+		}
+
+		printDependencies(w, "parents", service.Parents)
+		printDependencies(w, "children", service.Children)
+
+		if len(service.Logs) > 0 {
+			fmt.Fprintln(w, "logs:")
+			for _, line := range service.Logs {
+				fmt.Fprintf(w, "  %s\n", line.Line)
+			}
+		}
+	})
+}
+
+func printResources(w io.Writer, resources manager.ResourceOptions) {
+	if resources.MemoryMax != 0 {
+		fmt.Fprintf(w, "  memory max:\t%d\n", resources.MemoryMax)
+	}
+	if resources.MemoryHigh != 0 {
+		fmt.Fprintf(w, "  memory high:\t%d\n", resources.MemoryHigh)
+	}
+	if resources.CPUWeight != 0 {
+		fmt.Fprintf(w, "  cpu weight:\t%d\n", resources.CPUWeight)
+	}
+	if resources.CPUMax != "" {
+		fmt.Fprintf(w, "  cpu max:\t%s\n", resources.CPUMax)
+	}
+	if resources.PidsMax != 0 {
+		fmt.Fprintf(w, "  pids max:\t%d\n", resources.PidsMax)
+	}
+	if resources.IOWeight != 0 {
+		fmt.Fprintf(w, "  io weight:\t%d\n", resources.IOWeight)
+	}
+}
+
+func printTunables(w io.Writer, definition manager.ServiceOptions) {
+	rlimits := []struct {
+		name  string
+		value string
+	}{
+		{"nofile", definition.Rlimits.Nofile},
+		{"nproc", definition.Rlimits.Nproc},
+		{"core", definition.Rlimits.Core},
+		{"memlock", definition.Rlimits.Memlock},
+	}
+	for _, rlimit := range rlimits {
+		if rlimit.value != "" {
+			fmt.Fprintf(w, "  rlimit %s:\t%s\n", rlimit.name, rlimit.value)
+		}
+	}
+	if definition.Nice != 0 {
+		fmt.Fprintf(w, "  nice:\t%d\n", definition.Nice)
+	}
+	if definition.OOMScoreAdj != 0 {
+		fmt.Fprintf(w, "  oom score adj:\t%d\n", definition.OOMScoreAdj)
+	}
+	if len(definition.CPUAffinity) > 0 {
+		fmt.Fprintf(w, "  cpu affinity:\t%v\n", definition.CPUAffinity)
+	}
+	if definition.IOPrio != "" {
+		fmt.Fprintf(w, "  ioprio:\t%s\n", definition.IOPrio)
+	}
+}
+
+func printHooks(w io.Writer, kind string, hooks []string) {
+	for _, hook := range hooks {
+		fmt.Fprintf(w, "  %s:\t%s\n", kind, hook)
+	}
+}
+
+func printDependencies(w io.Writer, title string, dependencies map[string]manager.Status) {
+	if len(dependencies) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(dependencies))
+	for name := range dependencies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "%s:\n", title)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s:\t%s\n", name, dependencies[name])
+	}
+}
+
+func formatExit(exit manager.ExitInfo) string {
+	if exit.Signal != "" {
+		return fmt.Sprintf("killed by %s at %s", exit.Signal, exit.Time.Format(time.RFC3339))
+	}
+	return fmt.Sprintf("exit code %d at %s", exit.Code, exit.Time.Format(time.RFC3339))
+}
+
+func formatReload(reload manager.ReloadInfo) string {
+	if reload.Error != "" {
+		return fmt.Sprintf("failed at %s: %s", reload.Time.Format(time.RFC3339), reload.Error)
+	}
+	return fmt.Sprintf("succeeded at %s", reload.Time.Format(time.RFC3339))
+}
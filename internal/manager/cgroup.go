@@ -0,0 +1,275 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultCgroupRoot is the cgroup v2 directory the cgroups of services are created in
+	DefaultCgroupRoot = "/sys/fs/cgroup/sminit"
+	// defaultCPUPeriod is the period in microseconds of cpu_max limits given as a percentage
+	defaultCPUPeriod = 100000
+)
+
+// cgroupControllers are the controllers enabled for the cgroups of services
+var cgroupControllers = []string{"cpu", "memory", "pids", "io"}
+
+// ResourceOptions are the limits applied to the cgroup of a service, unset limits are not applied
+type ResourceOptions struct {
+	// MemoryMax is the memory usage hard limit, like 512M, processes are killed by the kernel if it is exceeded
+	MemoryMax Size `yaml:"memory_max,omitempty"`
+	// MemoryHigh is the memory usage throttle limit, like 256M
+	MemoryHigh Size `yaml:"memory_high,omitempty"`
+	// CPUWeight is the relative share of cpu time, between 1 and 10000, the kernel default is 100
+	CPUWeight uint64 `yaml:"cpu_weight,omitempty"`
+	// CPUMax is the maximum cpu time, either as a percentage of one cpu like 150%, or as "quota period" in microseconds like "50000 100000"
+	CPUMax string `yaml:"cpu_max,omitempty"`
+	// PidsMax is the maximum number of processes and threads
+	PidsMax int64 `yaml:"pids_max,omitempty"`
+	// IOWeight is the relative share of io, between 1 and 10000, the kernel default is 100
+	IOWeight uint64 `yaml:"io_weight,omitempty"`
+}
+
+// ResourceUsage is the resource accounting of the cgroup of a service
+type ResourceUsage struct {
+	// MemoryCurrent is the memory used by all processes of the service, in bytes
+	MemoryCurrent int64
+	// CPUUsage is the cpu time used by all processes of the service
+	CPUUsage time.Duration
+	// Tasks is the number of processes and threads of the service
+	Tasks int64
+}
+
+// IsSet returns true if the service has resource limits
+func (r ResourceOptions) IsSet() bool {
+	return r != ResourceOptions{}
+}
+
+func (r ResourceOptions) validate() error {
+	if r.CPUWeight > 10000 {
+		return errors.Errorf("cpu_weight %d should be between 1 and 10000", r.CPUWeight)
+	}
+	if r.IOWeight > 10000 {
+		return errors.Errorf("io_weight %d should be between 1 and 10000", r.IOWeight)
+	}
+	if r.PidsMax < 0 {
+		return errors.Errorf("pids_max %d should not be negative", r.PidsMax)
+	}
+	if r.CPUMax != "" {
+		if _, err := parseCPUMax(r.CPUMax); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limits returns the contents of the cgroup interface files that apply the limits, keyed by file name
+func (r ResourceOptions) limits() map[string]string {
+	limits := map[string]string{}
+	if r.MemoryMax != 0 {
+		limits["memory.max"] = strconv.FormatInt(int64(r.MemoryMax), 10)
+	}
+	if r.MemoryHigh != 0 {
+		limits["memory.high"] = strconv.FormatInt(int64(r.MemoryHigh), 10)
+	}
+	if r.CPUWeight != 0 {
+		limits["cpu.weight"] = strconv.FormatUint(r.CPUWeight, 10)
+	}
+	if r.CPUMax != "" {
+		// the value is validated when the service is loaded
+		limits["cpu.max"], _ = parseCPUMax(r.CPUMax)
+	}
+	if r.PidsMax != 0 {
+		limits["pids.max"] = strconv.FormatInt(r.PidsMax, 10)
+	}
+	if r.IOWeight != 0 {
+		limits["io.weight"] = fmt.Sprintf("default %d", r.IOWeight)
+	}
+	return limits
+}
+
+// parseCPUMax converts a cpu_max limit to the format of cpu.max, "quota period" in microseconds
+func parseCPUMax(cpuMax string) (string, error) {
+	cpuMax = strings.TrimSpace(cpuMax)
+	if cpuMax == "max" {
+		return "max", nil
+	}
+
+	if percent := strings.TrimSuffix(cpuMax, "%"); percent != cpuMax {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p <= 0 {
+			return "", errors.Errorf("invalid cpu_max %s, the percentage should be a positive number", cpuMax)
+		}
+		return fmt.Sprintf("%d %d", int64(p*defaultCPUPeriod/100), defaultCPUPeriod), nil
+	}
+
+	fields := strings.Fields(cpuMax)
+	if len(fields) == 0 || len(fields) > 2 {
+		return "", errors.Errorf("invalid cpu_max %s, should be a percentage like 50%%, or \"quota period\" in microseconds", cpuMax)
+	}
+	for _, f := range fields {
+		if n, err := strconv.ParseUint(f, 10, 64); err != nil || n == 0 {
+			return "", errors.Errorf("invalid cpu_max %s, should be a percentage like 50%%, or \"quota period\" in microseconds", cpuMax)
+		}
+	}
+	if len(fields) == 1 {
+		fields = append(fields, strconv.Itoa(defaultCPUPeriod))
+	}
+	return strings.Join(fields, " "), nil
+}
+
+// setupCgroupRoot creates the cgroup the cgroups of services are created in, and enables the controllers they need.
+// it fails if root is not in a cgroup v2 hierarchy.
+func setupCgroupRoot(root string) error {
+	parent := path.Dir(root)
+	if _, err := os.Stat(path.Join(parent, "cgroup.controllers")); err != nil {
+		return errors.Errorf("%s is not in a cgroup v2 hierarchy", parent)
+	}
+
+	err := os.MkdirAll(root, 0755)
+	if err != nil {
+		return errors.Wrapf(err, "could not create cgroup %s", root)
+	}
+
+	// controllers are enabled one by one, so a controller that is not available does not prevent the others from being enabled
+	for _, dir := range []string{parent, root} {
+		for _, controller := range cgroupControllers {
+			err := os.WriteFile(path.Join(dir, "cgroup.subtree_control"), []byte("+"+controller), 0644)
+			if err != nil {
+				SminitLog.Debug().Msgf("could not enable %s controller in cgroup %s. %s", controller, dir, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+// setCgroupRoot makes the manager create a cgroup for each service in root, processes are placed in the cgroup of their service.
+// if root could not be set up, services are not placed in cgroups, and their resource limits are not applied.
+// it should be called before services are fired.
+func (m *Manager) setCgroupRoot(root string) {
+	if err := setupCgroupRoot(root); err != nil {
+		SminitLog.Warn().Msgf("services are not placed in cgroups, resource limits are not applied. %s", err.Error())
+		return
+	}
+	m.cgroupRoot = root
+}
+
+// prepareCgroup creates the cgroup of the service and applies its limits, it returns an empty path if cgroups are not used
+func (m *Manager) prepareCgroup(service *Service) (string, error) {
+	if m.cgroupRoot == "" {
+		return "", nil
+	}
+
+	dir := path.Join(m.cgroupRoot, service.Name)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not create cgroup %s", dir)
+	}
+
+	for file, value := range service.options.Resources.limits() {
+		err := os.WriteFile(path.Join(dir, file), []byte(value), 0644)
+		if err != nil {
+			return dir, errors.Wrapf(err, "could not set %s of cgroup %s to %s", file, dir, value)
+		}
+	}
+
+	service.mut.Lock()
+	service.cgroup = dir
+	service.mut.Unlock()
+	return dir, nil
+}
+
+// removeCgroup removes the cgroup of the service, once it has no processes left
+func (s *Service) removeCgroup() {
+	s.mut.Lock()
+	dir := s.cgroup
+	s.cgroup = ""
+	s.mut.Unlock()
+
+	if dir == "" {
+		return
+	}
+	if err := os.Remove(dir); err != nil {
+		s.logger().Debug().Msgf("could not remove cgroup %s. %s", dir, err.Error())
+	}
+}
+
+// addToCgroup moves the process with the given pid to the cgroup at dir. processes of services are placed in their cgroup by the exec helper,
+// before the command of the service is executed.
+func addToCgroup(dir string, pid int) error {
+	return os.WriteFile(path.Join(dir, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644)
+}
+
+// killCgroup kills all processes in the cgroup at dir. cgroup.kill is used if the kernel supports it,
+// otherwise the processes listed in cgroup.procs are killed one by one.
+func killCgroup(dir string) error {
+	if err := os.WriteFile(path.Join(dir, "cgroup.kill"), []byte("1"), 0644); err == nil {
+		return nil
+	}
+
+	content, err := os.ReadFile(path.Join(dir, "cgroup.procs"))
+	if err != nil {
+		return errors.Wrapf(err, "could not read processes of cgroup %s", dir)
+	}
+	for _, field := range strings.Fields(string(content)) {
+		pid, err := strconv.Atoi(field)
+		if err != nil {
+			continue
+		}
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+	}
+	return nil
+}
+
+// readCgroupUsage reads the resource accounting of the cgroup at dir
+func readCgroupUsage(dir string) (ResourceUsage, error) {
+	usage := ResourceUsage{}
+
+	memory, err := readCgroupInt(dir, "memory.current")
+	if err != nil {
+		return usage, err
+	}
+	usage.MemoryCurrent = memory
+
+	tasks, err := readCgroupInt(dir, "pids.current")
+	if err != nil {
+		return usage, err
+	}
+	usage.Tasks = tasks
+
+	content, err := os.ReadFile(path.Join(dir, "cpu.stat"))
+	if err != nil {
+		return usage, errors.Wrapf(err, "could not read cpu.stat of cgroup %s", dir)
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 2 && fields[0] == "usage_usec" {
+			usec, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				return usage, errors.Wrapf(err, "invalid usage_usec in cpu.stat of cgroup %s", dir)
+			}
+			usage.CPUUsage = time.Duration(usec) * time.Microsecond
+		}
+	}
+	return usage, nil
+}
+
+func readCgroupInt(dir string, file string) (int64, error) {
+	content, err := os.ReadFile(path.Join(dir, file))
+	if err != nil {
+		return 0, errors.Wrapf(err, "could not read %s of cgroup %s", file, dir)
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(string(content)), 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s of cgroup %s", file, dir)
+	}
+	return n, nil
+}
@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCgroup(t *testing.T) {
+	t.Run("cpu_max", func(t *testing.T) {
+		tests := map[string]string{
+			"max":          "max",
+			"50%":          "50000 100000",
+			"150%":         "150000 100000",
+			"20000":        "20000 100000",
+			"20000 50000":  "20000 50000",
+			" 5000 10000 ": "5000 10000",
+		}
+		for cpuMax, want := range tests {
+			got, err := parseCPUMax(cpuMax)
+			assert.NoError(t, err, cpuMax)
+			assert.Equal(t, want, got, cpuMax)
+		}
+
+		for _, cpuMax := range []string{"", "0%", "-5%", "abc", "1 2 3", "0 100000"} {
+			_, err := parseCPUMax(cpuMax)
+			assert.Error(t, err, cpuMax)
+		}
+	})
+
+	t.Run("limits", func(t *testing.T) {
+		resources := ResourceOptions{
+			MemoryMax:  512 << 20,
+			MemoryHigh: 256 << 20,
+			CPUWeight:  200,
+			CPUMax:     "50%",
+			PidsMax:    64,
+			IOWeight:   50,
+		}
+		assert.NoError(t, resources.validate())
+		assert.Equal(t, map[string]string{
+			"memory.max":  "536870912",
+			"memory.high": "268435456",
+			"cpu.weight":  "200",
+			"cpu.max":     "50000 100000",
+			"pids.max":    "64",
+			"io.weight":   "default 50",
+		}, resources.limits())
+
+		assert.Empty(t, ResourceOptions{}.limits())
+		assert.Error(t, ResourceOptions{CPUWeight: 10001}.validate())
+		assert.Error(t, ResourceOptions{IOWeight: 10001}.validate())
+		assert.Error(t, ResourceOptions{PidsMax: -1}.validate())
+		assert.Error(t, ResourceOptions{CPUMax: "fast"}.validate())
+	})
+
+	t.Run("setup_root", func(t *testing.T) {
+		parent := t.TempDir()
+		root := path.Join(parent, "sminit")
+
+		// the parent is not a cgroup v2 hierarchy
+		assert.Error(t, setupCgroupRoot(root))
+
+		assert.NoError(t, os.WriteFile(path.Join(parent, "cgroup.controllers"), []byte("cpu io memory pids"), 0644))
+		assert.NoError(t, setupCgroupRoot(root))
+		assert.DirExists(t, root)
+	})
+
+	t.Run("usage", func(t *testing.T) {
+		dir := t.TempDir()
+		assert.NoError(t, os.WriteFile(path.Join(dir, "memory.current"), []byte("4096\n"), 0644))
+		assert.NoError(t, os.WriteFile(path.Join(dir, "pids.current"), []byte("3\n"), 0644))
+		assert.NoError(t, os.WriteFile(path.Join(dir, "cpu.stat"), []byte("usage_usec 1500000\nuser_usec 1000000\nsystem_usec 500000\n"), 0644))
+
+		usage, err := readCgroupUsage(dir)
+		assert.NoError(t, err)
+		assert.Equal(t, ResourceUsage{MemoryCurrent: 4096, CPUUsage: 1500 * time.Millisecond, Tasks: 3}, usage)
+
+		_, err = readCgroupUsage(t.TempDir())
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,143 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// kernelCmdlinePath is the file the kernel command line is read from
+var kernelCmdlinePath = "/proc/cmdline"
+
+// ConditionOptions are checked before a service is started, the service is skipped if one of them is not met
+type ConditionOptions struct {
+	// PathExists are paths that should exist
+	PathExists []string `yaml:"path_exists,omitempty"`
+	// PathNotExists are paths that should not exist
+	PathNotExists []string `yaml:"path_not_exists,omitempty"`
+	// FileNotEmpty are regular files that should exist and not be empty
+	FileNotEmpty []string `yaml:"file_not_empty,omitempty"`
+	// DirNotEmpty are directories that should exist and contain at least one entry
+	DirNotEmpty []string `yaml:"dir_not_empty,omitempty"`
+	// EnvSet are environment variables that should be set
+	EnvSet []string `yaml:"env_set,omitempty"`
+	// KernelCmdline are flags like "debug", or "key=value" pairs, that should be on the kernel command line
+	KernelCmdline []string `yaml:"kernel_cmdline,omitempty"`
+	// Executable are paths, or names looked up in PATH, of executables that should exist
+	Executable []string `yaml:"executable,omitempty"`
+}
+
+// IsSet returns true if the service has conditions
+func (c ConditionOptions) IsSet() bool {
+	return len(c.PathExists)+len(c.PathNotExists)+len(c.FileNotEmpty)+len(c.DirNotEmpty)+
+		len(c.EnvSet)+len(c.KernelCmdline)+len(c.Executable) > 0
+}
+
+// check returns a description of the first condition that is not met, or an empty string if all conditions are met
+func (c ConditionOptions) check() string {
+	for _, p := range c.PathExists {
+		if _, err := os.Stat(p); err != nil {
+			return fmt.Sprintf("path_exists %s: path does not exist", p)
+		}
+	}
+
+	for _, p := range c.PathNotExists {
+		if _, err := os.Stat(p); err == nil {
+			return fmt.Sprintf("path_not_exists %s: path exists", p)
+		}
+	}
+
+	for _, p := range c.FileNotEmpty {
+		info, err := os.Stat(p)
+		if err != nil || !info.Mode().IsRegular() {
+			return fmt.Sprintf("file_not_empty %s: not a regular file", p)
+		}
+		if info.Size() == 0 {
+			return fmt.Sprintf("file_not_empty %s: file is empty", p)
+		}
+	}
+
+	for _, p := range c.DirNotEmpty {
+		if !dirHasEntries(p) {
+			return fmt.Sprintf("dir_not_empty %s: directory does not exist or is empty", p)
+		}
+	}
+
+	for _, name := range c.EnvSet {
+		if _, ok := os.LookupEnv(name); !ok {
+			return fmt.Sprintf("env_set %s: variable is not set", name)
+		}
+	}
+
+	if len(c.KernelCmdline) > 0 {
+		content, err := os.ReadFile(kernelCmdlinePath)
+		if err != nil {
+			return fmt.Sprintf("kernel_cmdline: could not read %s", kernelCmdlinePath)
+		}
+		for _, flag := range c.KernelCmdline {
+			if !hasKernelFlag(string(content), flag) {
+				return fmt.Sprintf("kernel_cmdline %s: flag is not set", flag)
+			}
+		}
+	}
+
+	for _, p := range c.Executable {
+		if _, err := exec.LookPath(p); err != nil {
+			return fmt.Sprintf("executable %s: not found", p)
+		}
+	}
+
+	return ""
+}
+
+func dirHasEntries(p string) bool {
+	dir, err := os.Open(p)
+	if err != nil {
+		return false
+	}
+	defer dir.Close()
+
+	_, err = dir.Readdirnames(1)
+	return err == nil
+}
+
+// hasKernelFlag returns true if cmdline has flag. a flag without a value, like "debug", also matches "debug=..." on the command line.
+func hasKernelFlag(cmdline string, flag string) bool {
+	for _, field := range strings.Fields(cmdline) {
+		if field == flag {
+			return true
+		}
+		if !strings.Contains(flag, "=") && strings.HasPrefix(field, flag+"=") {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConditions checks the conditions of the service before it is started. if one of them is not met, the service is skipped,
+// and its dependents are started as if it was successful.
+func (m *Manager) checkConditions(service *Service) bool {
+	reason := service.options.Conditions.check()
+	if reason == "" {
+		return true
+	}
+
+	service.logger().Info().Msgf("skipping service %s, condition %s", service.Name, reason)
+	service.skip(reason)
+	m.startEligibleChildren(service.Name)
+	return false
+}
+
+func (s *Service) skip(reason string) {
+	s.mut.Lock()
+	s.skipReason = reason
+	event := s.setStatusLocked(Skipped)
+	s.mut.Unlock()
+
+	if event != nil {
+		event.Reason = ReasonConditionFailed
+		event.Error = reason
+		s.events.publish(*event)
+	}
+}
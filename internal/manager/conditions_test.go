@@ -0,0 +1,68 @@
+package manager
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConditions(t *testing.T) {
+	dir := t.TempDir()
+	emptyFile := path.Join(dir, "empty")
+	assert.NoError(t, os.WriteFile(emptyFile, nil, 0644))
+	file := path.Join(dir, "file")
+	assert.NoError(t, os.WriteFile(file, []byte("content"), 0644))
+	emptyDir := path.Join(dir, "empty_dir")
+	assert.NoError(t, os.Mkdir(emptyDir, 0755))
+	missing := path.Join(dir, "missing")
+
+	cmdline := path.Join(dir, "cmdline")
+	assert.NoError(t, os.WriteFile(cmdline, []byte("root=/dev/sda1 quiet console=ttyS0\n"), 0644))
+	oldCmdlinePath := kernelCmdlinePath
+	kernelCmdlinePath = cmdline
+	defer func() { kernelCmdlinePath = oldCmdlinePath }()
+
+	t.Setenv("SMINIT_TEST_CONDITION", "")
+
+	tests := []struct {
+		name       string
+		conditions ConditionOptions
+		met        bool
+	}{
+		{"none", ConditionOptions{}, true},
+		{"path exists", ConditionOptions{PathExists: []string{file, emptyDir}}, true},
+		{"path does not exist", ConditionOptions{PathExists: []string{missing}}, false},
+		{"path not exists", ConditionOptions{PathNotExists: []string{missing}}, true},
+		{"path not exists but exists", ConditionOptions{PathNotExists: []string{file}}, false},
+		{"file not empty", ConditionOptions{FileNotEmpty: []string{file}}, true},
+		{"file is empty", ConditionOptions{FileNotEmpty: []string{emptyFile}}, false},
+		{"file not empty is a directory", ConditionOptions{FileNotEmpty: []string{dir}}, false},
+		{"dir not empty", ConditionOptions{DirNotEmpty: []string{dir}}, true},
+		{"dir is empty", ConditionOptions{DirNotEmpty: []string{emptyDir}}, false},
+		{"dir does not exist", ConditionOptions{DirNotEmpty: []string{missing}}, false},
+		{"env set", ConditionOptions{EnvSet: []string{"SMINIT_TEST_CONDITION"}}, true},
+		{"env not set", ConditionOptions{EnvSet: []string{"SMINIT_TEST_CONDITION_MISSING"}}, false},
+		{"kernel flag", ConditionOptions{KernelCmdline: []string{"quiet"}}, true},
+		{"kernel flag with value", ConditionOptions{KernelCmdline: []string{"console=ttyS0"}}, true},
+		{"kernel flag name of pair", ConditionOptions{KernelCmdline: []string{"console"}}, true},
+		{"kernel flag with other value", ConditionOptions{KernelCmdline: []string{"console=tty0"}}, false},
+		{"kernel flag missing", ConditionOptions{KernelCmdline: []string{"debug"}}, false},
+		{"executable in path", ConditionOptions{Executable: []string{"sh"}}, true},
+		{"executable missing", ConditionOptions{Executable: []string{missing}}, false},
+		{"executable not executable", ConditionOptions{Executable: []string{file}}, false},
+		{"all conditions should be met", ConditionOptions{PathExists: []string{file}, EnvSet: []string{"SMINIT_TEST_CONDITION_MISSING"}}, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			reason := tc.conditions.check()
+			if tc.met {
+				assert.Empty(t, reason)
+			} else {
+				assert.NotEmpty(t, reason)
+			}
+		})
+	}
+}
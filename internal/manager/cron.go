@@ -0,0 +1,203 @@
+package manager
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// cronSearchLimit is how far in the future the next time matching a cron expression is searched for
+const cronSearchLimit = 5 * 366 * 24 * time.Hour
+
+// cronShortcuts are the predefined cron expressions
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var monthNames = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+var weekdayNames = map[string]int{
+	"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+}
+
+// cronSchedule is a parsed cron expression with the fields minute, hour, day of month, month and day of week.
+// each field holds the values it matches.
+type cronSchedule struct {
+	minute map[int]bool
+	hour   map[int]bool
+	dom    map[int]bool
+	month  map[int]bool
+	dow    map[int]bool
+	// anyDom and anyDow are true if the day of month or day of week field starts with *, so days are matched by the other field only
+	anyDom bool
+	anyDow bool
+}
+
+// cronField describes the allowed values of a field of a cron expression
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names map[string]int
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: monthNames},
+	// 7 is accepted as sunday, and mapped to 0
+	{name: "day of week", min: 0, max: 7, names: weekdayNames},
+}
+
+// parseCron parses a standard five field cron expression like "*/15 9-17 * * mon-fri", or a shortcut like "@daily"
+func parseCron(expr string) (*cronSchedule, error) {
+	normalized := strings.TrimSpace(expr)
+	if shortcut, ok := cronShortcuts[strings.ToLower(normalized)]; ok {
+		normalized = shortcut
+	}
+
+	fields := strings.Fields(normalized)
+	if len(fields) != len(cronFields) {
+		return nil, errors.Errorf("invalid cron expression %q, should have %d fields", expr, len(cronFields))
+	}
+
+	values := make([]map[int]bool, len(cronFields))
+	for i, field := range fields {
+		v, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid cron expression %q", expr)
+		}
+		values[i] = v
+	}
+
+	if values[4][7] {
+		delete(values[4], 7)
+		values[4][0] = true
+	}
+
+	return &cronSchedule{
+		minute: values[0],
+		hour:   values[1],
+		dom:    values[2],
+		month:  values[3],
+		dow:    values[4],
+		anyDom: strings.HasPrefix(fields[2], "*"),
+		anyDow: strings.HasPrefix(fields[4], "*"),
+	}, nil
+}
+
+// parse parses a field made of comma separated items, each item is *, a value, or a range, optionally followed by a /step
+func (f cronField) parse(field string) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.Index(item, "/"); i >= 0 {
+			rangePart = item[:i]
+			s, err := strconv.Atoi(item[i+1:])
+			if err != nil || s <= 0 {
+				return nil, errors.Errorf("invalid step in %s field %q", f.name, item)
+			}
+			step = s
+		}
+
+		start, end := f.min, f.max
+		switch {
+		case rangePart == "*":
+		case strings.Contains(rangePart, "-"):
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if start, err = f.value(bounds[0]); err != nil {
+				return nil, err
+			}
+			if end, err = f.value(bounds[1]); err != nil {
+				return nil, err
+			}
+			if start > end {
+				return nil, errors.Errorf("invalid range in %s field %q", f.name, item)
+			}
+		default:
+			var err error
+			if start, err = f.value(rangePart); err != nil {
+				return nil, err
+			}
+			// a single value with a step, like 5/15, means every step starting at the value
+			end = start
+			if step > 1 {
+				end = f.max
+			}
+		}
+
+		for v := start; v <= end; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, errors.Errorf("invalid %s %q, should be between %d and %d", f.name, s, f.min, f.max)
+	}
+	return v, nil
+}
+
+// next returns the first time after t matching the schedule, or the zero time if there is none in cronSearchLimit
+func (c *cronSchedule) next(t time.Time) time.Time {
+	limit := t.Add(cronSearchLimit)
+	t = t.Truncate(time.Minute).Add(time.Minute)
+
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.matchesDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.minute[t.Minute()] {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchesDay follows cron semantics: if both day of month and day of week are restricted, a day matching either of them matches
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	dom := c.dom[t.Day()]
+	dow := c.dow[int(t.Weekday())]
+
+	switch {
+	case c.anyDom && c.anyDow:
+		return true
+	case c.anyDom:
+		return dow
+	case c.anyDow:
+		return dom
+	default:
+		return dom || dow
+	}
+}
@@ -0,0 +1,81 @@
+package manager
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCron(t *testing.T) {
+	t.Run("invalid expressions", func(t *testing.T) {
+		for _, expr := range []string{
+			"",
+			"* * * *",
+			"* * * * * *",
+			"60 * * * *",
+			"* 24 * * *",
+			"* * 0 * *",
+			"* * * 13 *",
+			"* * * * 8",
+			"*/0 * * * *",
+			"5-1 * * * *",
+			"* * * foo *",
+			"@sometimes",
+		} {
+			_, err := parseCron(expr)
+			assert.Error(t, err, expr)
+		}
+	})
+
+	from := time.Date(2023, time.March, 15, 10, 7, 30, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		expr string
+		want time.Time
+	}{
+		{"every minute", "* * * * *", time.Date(2023, time.March, 15, 10, 8, 0, 0, time.UTC)},
+		{"step", "*/15 * * * *", time.Date(2023, time.March, 15, 10, 15, 0, 0, time.UTC)},
+		{"value with step", "5/20 * * * *", time.Date(2023, time.March, 15, 10, 25, 0, 0, time.UTC)},
+		{"list", "0 9,17 * * *", time.Date(2023, time.March, 15, 17, 0, 0, 0, time.UTC)},
+		{"range", "30 11-13 * * *", time.Date(2023, time.March, 15, 11, 30, 0, 0, time.UTC)},
+		{"daily", "@daily", time.Date(2023, time.March, 16, 0, 0, 0, 0, time.UTC)},
+		{"monthly", "@monthly", time.Date(2023, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{"month names", "0 0 1 jan,jun *", time.Date(2023, time.June, 1, 0, 0, 0, 0, time.UTC)},
+		// march 15 2023 is a wednesday
+		{"weekday names", "0 8 * * mon-tue", time.Date(2023, time.March, 20, 8, 0, 0, 0, time.UTC)},
+		{"sunday as 7", "0 0 * * 7", time.Date(2023, time.March, 19, 0, 0, 0, 0, time.UTC)},
+		{"day of month or day of week", "0 0 20 * fri", time.Date(2023, time.March, 17, 0, 0, 0, 0, time.UTC)},
+		{"leap day", "0 0 29 2 *", time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			cron, err := parseCron(tc.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, cron.next(from))
+		})
+	}
+
+	t.Run("no next run", func(t *testing.T) {
+		cron, err := parseCron("0 0 31 2 *")
+		assert.NoError(t, err)
+		assert.True(t, cron.next(from).IsZero())
+	})
+
+	t.Run("every anchored on last run", func(t *testing.T) {
+		schedule := ScheduleOptions{Every: time.Hour}
+		now := from
+
+		// the next run keeps the phase of the last run
+		lastRun := now.Add(-150 * time.Minute)
+		assert.Equal(t, now.Add(30*time.Minute), schedule.next(schedule.anchor(lastRun, now)))
+		lastRun = now.Add(-20 * time.Minute)
+		assert.Equal(t, now.Add(40*time.Minute), schedule.next(schedule.anchor(lastRun, now)))
+
+		// without a last run, or for cron schedules, runs are computed from now
+		assert.Equal(t, now, schedule.anchor(time.Time{}, now))
+		assert.Equal(t, now, ScheduleOptions{Cron: "@daily"}.anchor(lastRun, now))
+	})
+}
@@ -0,0 +1,53 @@
+package manager
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Disable stops a service and prevents it from being started until it is enabled
+func (m *Manager) Disable(name string) error {
+	m.ops.Lock()
+	defer m.ops.Unlock()
+
+	service, ok := m.getService(name)
+	if !ok {
+		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	if service.isDisabled() {
+		return errors.Wrapf(ErrBadRequest, "service %s is already disabled", name)
+	}
+
+	service.stop()
+	service.changeStatus(Disabled)
+	m.state.setStopped(name, false)
+	m.state.setDisabled(name, true, service.options.Disabled)
+
+	SminitLog.Info().Msgf("service %s is disabled", name)
+	return nil
+}
+
+// Enable allows a disabled service to be started, and starts it if its parents are running or successful
+func (m *Manager) Enable(name string) error {
+	m.ops.Lock()
+	defer m.ops.Unlock()
+
+	service, ok := m.getService(name)
+	if !ok {
+		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	if !service.isDisabled() {
+		return errors.Wrapf(ErrBadRequest, "service %s is not disabled", name)
+	}
+
+	service.changeStatus(Pending)
+	m.state.setDisabled(name, false, service.options.Disabled)
+
+	SminitLog.Info().Msgf("service %s is enabled", name)
+
+	if m.isEligibleToRun(name) {
+		service.startSignal <- true
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the kind of an event emitted by sminit
+type EventType string
+
+const (
+	// EventStatus is emitted whenever the status of a service changes
+	EventStatus EventType = "status"
+	// EventAdd is emitted when a service is added to the tracked services
+	EventAdd EventType = "add"
+	// EventDelete is emitted when a service is deleted from the tracked services
+	EventDelete EventType = "delete"
+	// EventReload is emitted when a service is reloaded, with the reload error if it failed
+	EventReload EventType = "reload"
+	// EventHealth is emitted with the result of each health check of a service
+	EventHealth EventType = "health"
+	// EventExit is emitted whenever a process of a service terminates
+	EventExit EventType = "exit"
+)
+
+// maxEvents is the number of recent events kept in memory
+const maxEvents = 1000
+
+// Event is something that happened to a service
+type Event struct {
+	Time    time.Time
+	Type    EventType
+	Service string
+	// OldStatus and NewStatus are set for status events
+	OldStatus Status
+	NewStatus Status
+	// PID is the process id of the service when the event happened, 0 if there was none
+	PID    int
+	Reason Reason
+	// Healthy is set for health events
+	Healthy bool
+	// Error is set for failed reload events, and describes the condition that was not met for skipped status events
+	Error string
+	// Exit is set for exit events
+	Exit *ExitInfo
+}
+
+// eventBus keeps the recent events of all services, and sends new events to followers
+type eventBus struct {
+	events []Event
+	// journal is nil unless events are persisted
+	journal *journal
+
+	subscribers map[chan Event]string
+	mut         sync.Mutex
+}
+
+func newEventBus() *eventBus {
+	return &eventBus{
+		subscribers: map[chan Event]string{},
+	}
+}
+
+// publish records the event and sends it to followers. events are dropped for followers that fall behind.
+func (b *eventBus) publish(event Event) {
+	if b == nil {
+		return
+	}
+
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	b.publishLocked(event)
+
+	// the journal is written holding the lock, so events are persisted in the order they are published
+	if b.journal != nil && isJournaled(event) {
+		if err := b.journal.append(event); err != nil {
+			SminitLog.Error().Msgf("failed to write event to journal. %s", err.Error())
+		}
+	}
+}
+
+// isJournaled returns true if the event is persisted to the journal, only status changes and exits are kept
+func isJournaled(event Event) bool {
+	return event.Type == EventStatus || event.Type == EventExit
+}
+
+func (b *eventBus) publishLocked(event Event) {
+	b.events = append(b.events, event)
+	if len(b.events) > maxEvents {
+		b.events = b.events[len(b.events)-maxEvents:]
+	}
+
+	for ch, service := range b.subscribers {
+		if service != "" && service != event.Service {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// recent returns the recent events of the service with the given name, or of all services if name is empty
+func (b *eventBus) recent(service string) []Event {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.recentLocked(service)
+}
+
+func (b *eventBus) recentLocked(service string) []Event {
+	ret := []Event{}
+	for _, event := range b.events {
+		if service != "" && event.Service != service {
+			continue
+		}
+		ret = append(ret, event)
+	}
+	return ret
+}
+
+// follow returns the recent events of the service with the given name, or of all services if name is empty,
+// and a channel that receives every matching event published afterwards. cancel should be called to stop receiving events.
+func (b *eventBus) follow(service string) (events []Event, ch <-chan Event, cancel func()) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	sub := make(chan Event, subscriberBufferSize)
+	b.subscribers[sub] = service
+
+	cancel = func() {
+		b.mut.Lock()
+		defer b.mut.Unlock()
+		delete(b.subscribers, sub)
+	}
+
+	return b.recentLocked(service), sub, cancel
+}
+
+// Events returns the recent events of the service with the given name, or of all services if name is empty
+func (m *Manager) Events(name string) []Event {
+	return m.events.recent(name)
+}
+
+// FollowEvents returns the recent events of the service with the given name, or of all services if name is empty,
+// and a channel that receives every matching event published afterwards. cancel should be called to stop receiving events.
+func (m *Manager) FollowEvents(name string) ([]Event, <-chan Event, func()) {
+	return m.events.follow(name)
+}
@@ -0,0 +1,175 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// execHelperEnv holds the options applied by the exec helper, it is only set for sminit executed as an exec helper
+	execHelperEnv = "SMINIT_EXEC_HELPER"
+	// execHelperPath is the path the exec helper is executed from, it is the running sminit binary even if it was replaced by an upgrade
+	execHelperPath = "/proc/self/exe"
+	// execStatusFd is the file descriptor the exec helper reports why it could not execute the command on, it is closed once the command is executed
+	execStatusFd = 3
+)
+
+// execOptions are applied by the exec helper to itself before it executes the command of a service,
+// so they apply to the process of the service from its first instruction
+type execOptions struct {
+	// Path is the path of the executable of the command
+	Path string
+	// Cgroup is the path of the cgroup the process is placed in, empty if there is none
+	Cgroup      string `json:",omitempty"`
+	Rlimits     RlimitOptions
+	Nice        int    `json:",omitempty"`
+	OOMScoreAdj int    `json:",omitempty"`
+	CPUAffinity []int  `json:",omitempty"`
+	IOPrio      string `json:",omitempty"`
+}
+
+// execOptions returns the options applied to the process of the service before its command is executed
+func (s *Service) execOptions(cgroup string) execOptions {
+	return execOptions{
+		Cgroup:      cgroup,
+		Rlimits:     s.options.Rlimits,
+		Nice:        s.options.Nice,
+		OOMScoreAdj: s.options.OOMScoreAdj,
+		CPUAffinity: s.options.CPUAffinity,
+		IOPrio:      s.options.IOPrio,
+	}
+}
+
+// needsHelper returns true if the options should be applied by the exec helper
+func (o execOptions) needsHelper() bool {
+	return o.Cgroup != "" || len(o.Rlimits.limits()) > 0 || o.Nice != 0 || o.OOMScoreAdj != 0 || len(o.CPUAffinity) > 0 || o.IOPrio != ""
+}
+
+// RunExecHelper executes the command of a service after applying the options of its process, if sminit was executed as an exec helper.
+// it does not return in that case, and does nothing otherwise. it should be called before anything else in main.
+func RunExecHelper() {
+	value, ok := os.LookupEnv(execHelperEnv)
+	if !ok {
+		return
+	}
+	// the command should not get the environment variable
+	os.Unsetenv(execHelperEnv)
+	// the command is executed from the thread the options are applied on
+	runtime.LockOSThread()
+
+	status := os.NewFile(execStatusFd, "exec status")
+	unix.CloseOnExec(execStatusFd)
+
+	err := execCommand(value, os.Args[1:])
+	fmt.Fprint(status, err.Error())
+	os.Exit(127)
+}
+
+// execCommand applies the encoded options, then replaces the exec helper with args. it only returns if it fails.
+func execCommand(value string, args []string) error {
+	opts := execOptions{}
+	if err := json.Unmarshal([]byte(value), &opts); err != nil {
+		return errors.Wrap(err, "could not unmarshal process options")
+	}
+
+	if opts.Cgroup != "" {
+		if err := addToCgroup(opts.Cgroup, os.Getpid()); err != nil {
+			return errors.Wrapf(err, "could not place process in cgroup %s", opts.Cgroup)
+		}
+	}
+	if err := applyTunables(opts); err != nil {
+		return err
+	}
+
+	err := syscall.Exec(opts.Path, args, os.Environ())
+	return errors.Wrapf(err, "could not execute %s", opts.Path)
+}
+
+// runEnv is what the processes of a run of a service and its hooks are started with
+type runEnv struct {
+	// env is added to the environment of sminit
+	env []string
+	// opts are applied before the command is executed
+	opts execOptions
+}
+
+// command returns the command that runs cmdStr with run, it should be started with startCommand.
+// if options should be applied before the command is executed, it is executed by the exec helper.
+func (run runEnv) command(ctx context.Context, cmdStr string) (*exec.Cmd, error) {
+	splittedCmd := strings.Split(cmdStr, " ")
+	cmd := exec.CommandContext(ctx, splittedCmd[0], splittedCmd[1:]...)
+	if len(run.env) > 0 {
+		cmd.Env = append(os.Environ(), run.env...)
+	}
+	if !run.opts.needsHelper() {
+		return cmd, nil
+	}
+
+	if cmd.Err != nil {
+		return nil, cmd.Err
+	}
+	opts := run.opts
+	opts.Path = cmd.Path
+	value, err := json.Marshal(opts)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal process options")
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%s", execHelperEnv, value))
+	// the helper gets the arguments of the command after its own name
+	cmd.Path = execHelperPath
+	cmd.Args = append([]string{os.Args[0]}, cmd.Args...)
+	return cmd, nil
+}
+
+// startCommand starts cmd. if it is executed by the exec helper, it returns once the helper has executed the command,
+// or the error the helper reported if it could not, after reaping the helper.
+func startCommand(cmd *exec.Cmd) error {
+	if cmd.Path != execHelperPath {
+		return cmd.Start()
+	}
+
+	status, statusWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer status.Close()
+	cmd.ExtraFiles = []*os.File{statusWriter}
+
+	err = cmd.Start()
+	// the write end is closed in sminit, so the status is read until the helper has executed the command or exited
+	statusWriter.Close()
+	if err != nil {
+		return err
+	}
+
+	if err := execStatus(status); err != nil {
+		_ = cmd.Wait()
+		return err
+	}
+	return nil
+}
+
+// execStatus waits until the exec helper executed the command or failed to, and returns the reason it failed if it did
+func execStatus(status *os.File) error {
+	reason, err := io.ReadAll(status)
+	if err != nil {
+		return errors.Wrap(err, "could not read status of exec helper")
+	}
+	if len(reason) > 0 {
+		return errors.New(string(reason))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// defaultHookTimeout is the maximum time a hook command is allowed to run, unless the service sets hook_timeout
+const defaultHookTimeout = 30 * time.Second
+
+const (
+	hookStartPre  = "exec_start_pre"
+	hookStartPost = "exec_start_post"
+	hookStop      = "exec_stop"
+	hookStopPost  = "exec_stop_post"
+)
+
+// runHooks runs the given hook commands of the service one after the other, and stops at the first one that fails.
+// each command is killed if it runs longer than the hook timeout of the service, or if ctx is cancelled.
+// commands are started with run like the process of the service, and if pid is not 0, they get it in MAINPID environment variable.
+func (s *Service) runHooks(ctx context.Context, kind string, hooks []string, pid int, run runEnv) error {
+	timeout := s.options.HookTimeout
+	if timeout == 0 {
+		timeout = defaultHookTimeout
+	}
+
+	for _, hook := range hooks {
+		s.logger().Debug().Msgf("running %s hook of service %s: %s", kind, s.Name, hook)
+		err := runHook(ctx, hook, pid, timeout, run)
+		if err != nil {
+			return errors.Wrapf(err, "%s hook %q of service %s failed", kind, hook, s.Name)
+		}
+	}
+	return nil
+}
+
+func runHook(ctx context.Context, hook string, pid int, timeout time.Duration, run runEnv) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if pid != 0 {
+		run.env = append(append([]string{}, run.env...), fmt.Sprintf("MAINPID=%d", pid))
+	}
+	cmd, err := run.command(ctx, hook)
+	if err != nil {
+		return err
+	}
+	var output bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &output, &output
+
+	err = startCommand(cmd)
+	if err == nil {
+		err = cmd.Wait()
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return errors.Errorf("timed out after %s", timeout)
+	}
+	if err != nil {
+		if output.Len() > 0 {
+			return errors.Wrapf(err, "%s", strings.TrimSpace(output.String()))
+		}
+		return err
+	}
+	return nil
+}
+
+// runStopHooks runs hooks that should not prevent the service from stopping, failures are only logged
+func (s *Service) runStopHooks(kind string, hooks []string, pid int, run runEnv) {
+	if err := s.runHooks(context.Background(), kind, hooks, pid, run); err != nil {
+		s.logger().Error().Msg(err.Error())
+	}
+}
+
+// waitRunning waits for the running process of the service to terminate.
+// if ctx is cancelled first, the exec_stop hooks of the service run with run before the process is killed.
+func (s *Service) waitRunning(ctx context.Context, process *serviceProcess, run runEnv) (syscall.WaitStatus, error) {
+	if len(s.options.ExecStop) == 0 {
+		return process.wait(ctx)
+	}
+
+	waitCtx, cancelWait := context.WithCancel(context.Background())
+	defer cancelWait()
+
+	exited := make(chan struct{})
+	hooksDone := make(chan struct{})
+	go func() {
+		defer close(hooksDone)
+		select {
+		case <-ctx.Done():
+			s.runStopHooks(hookStop, s.options.ExecStop, process.pid, run)
+			cancelWait()
+		case <-exited:
+		}
+	}()
+
+	status, err := process.wait(waitCtx)
+	close(exited)
+	// the process could exit while the hooks are running, the service is only stopped once they are done
+	<-hooksDone
+	return status, err
+}
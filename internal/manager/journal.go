@@ -0,0 +1,177 @@
+package manager
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultStateDir is the directory sminit keeps its state in, unless another directory is configured
+	DefaultStateDir = "/var/lib/sminit"
+	// journalFileName is the name of the file events are appended to, in the state directory
+	journalFileName = "journal"
+	// maxJournalSize is the size in bytes after which the journal is rotated. only one rotated journal is kept,
+	// so the journal never takes more than twice this size on disk.
+	maxJournalSize = 4 << 20
+)
+
+// journal appends events to a bounded file as json lines
+type journal struct {
+	path    string
+	file    *os.File
+	size    int64
+	maxSize int64
+	closed  bool
+	mut     sync.Mutex
+}
+
+func openJournal(stateDir string) (*journal, error) {
+	err := os.MkdirAll(stateDir, 0750)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create state directory %s", stateDir)
+	}
+
+	j := &journal{path: path.Join(stateDir, journalFileName), maxSize: maxJournalSize}
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+func (j *journal) open() error {
+	file, err := os.OpenFile(j.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.Wrapf(err, "could not open journal %s", j.path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "could not stat journal %s", j.path)
+	}
+
+	j.file = file
+	j.size = info.Size()
+	return nil
+}
+
+func (j *journal) append(event Event) error {
+	line, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal event")
+	}
+	line = append(line, '\n')
+
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	if j.closed {
+		return errors.Errorf("journal %s is closed", j.path)
+	}
+	// the journal is reopened if it could not be reopened after a rotation
+	if j.file == nil {
+		if err := j.open(); err != nil {
+			return err
+		}
+	}
+
+	if j.size+int64(len(line)) > j.maxSize {
+		if err := j.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := j.file.Write(line)
+	j.size += int64(n)
+	if err != nil {
+		return errors.Wrapf(err, "could not write to journal %s", j.path)
+	}
+	return nil
+}
+
+// rotate moves the journal to journal.1, replacing the previously rotated journal, and starts a new journal.
+// the journal is reopened even if it could not be moved, so events are still appended to it.
+func (j *journal) rotate() error {
+	err := j.file.Close()
+	j.file = nil
+	if err != nil {
+		SminitLog.Error().Msgf("failed to close journal %s. %s", j.path, err.Error())
+	}
+
+	rotateErr := os.Rename(j.path, j.path+".1")
+	if err := j.open(); err != nil {
+		return err
+	}
+	if rotateErr != nil {
+		// the journal keeps growing until it could be rotated
+		SminitLog.Error().Msgf("failed to rotate journal %s. %s", j.path, rotateErr.Error())
+	}
+	return nil
+}
+
+func (j *journal) close() error {
+	j.mut.Lock()
+	defer j.mut.Unlock()
+
+	j.closed = true
+	if j.file == nil {
+		return nil
+	}
+	err := j.file.Close()
+	j.file = nil
+	return err
+}
+
+// ReadJournal returns the events kept in the journal of the given state directory that happened after since,
+// for the service with the given name, or for all services if name is empty.
+func ReadJournal(stateDir, name string, since time.Time) ([]Event, error) {
+	journalPath := path.Join(stateDir, journalFileName)
+
+	events := []Event{}
+	for _, p := range []string{journalPath + ".1", journalPath} {
+		file, err := os.Open(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not open journal %s", p)
+		}
+
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			event := Event{}
+			// a line could be cut if sminit was killed while writing it, so invalid lines are skipped
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				continue
+			}
+			if name != "" && event.Service != name {
+				continue
+			}
+			if event.Time.Before(since) {
+				continue
+			}
+			events = append(events, event)
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read journal %s", p)
+		}
+	}
+
+	return events, nil
+}
+
+// setJournal makes the manager persist status and exit events to the journal
+func (m *Manager) setJournal(j *journal) {
+	m.events.mut.Lock()
+	defer m.events.mut.Unlock()
+	m.events.journal = j
+}
@@ -0,0 +1,149 @@
+package manager
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJournal(t *testing.T) {
+	t.Run("read_events", func(t *testing.T) {
+		stateDir := t.TempDir()
+		j, err := openJournal(stateDir)
+		assert.NoError(t, err)
+
+		start := time.Now()
+		assert.NoError(t, j.append(Event{Time: start.Add(-time.Hour), Type: EventStatus, Service: "s1", NewStatus: Failed}))
+		assert.NoError(t, j.append(Event{Time: start, Type: EventExit, Service: "s1", Exit: &ExitInfo{Code: 2}}))
+		assert.NoError(t, j.append(Event{Time: start, Type: EventStatus, Service: "s2", NewStatus: Running}))
+		assert.NoError(t, j.close())
+
+		events, err := ReadJournal(stateDir, "", time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, events, 3)
+
+		events, err = ReadJournal(stateDir, "s1", start.Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, 2, events[0].Exit.Code)
+
+		// events are appended to the existing journal after a restart
+		j, err = openJournal(stateDir)
+		assert.NoError(t, err)
+		assert.NoError(t, j.append(Event{Time: start, Type: EventDelete, Service: "s2"}))
+		assert.NoError(t, j.close())
+
+		events, err = ReadJournal(stateDir, "s2", time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, EventDelete, events[1].Type)
+	})
+
+	t.Run("rotate", func(t *testing.T) {
+		stateDir := t.TempDir()
+		j, err := openJournal(stateDir)
+		assert.NoError(t, err)
+		j.maxSize = 300
+
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, j.append(Event{Time: time.Now(), Type: EventHealth, Service: "s1", PID: i}))
+		}
+		assert.NoError(t, j.close())
+
+		info, err := os.Stat(path.Join(stateDir, journalFileName))
+		assert.NoError(t, err)
+		assert.LessOrEqual(t, info.Size(), int64(300))
+		assert.FileExists(t, path.Join(stateDir, journalFileName+".1"))
+
+		// the oldest events are dropped, and the remaining ones are in order
+		events, err := ReadJournal(stateDir, "s1", time.Time{})
+		assert.NoError(t, err)
+		assert.Less(t, len(events), 10)
+		assert.Equal(t, 9, events[len(events)-1].PID)
+		for i := 1; i < len(events); i++ {
+			assert.Equal(t, events[i-1].PID+1, events[i].PID)
+		}
+	})
+
+	t.Run("rotate_failure", func(t *testing.T) {
+		stateDir := t.TempDir()
+		j, err := openJournal(stateDir)
+		assert.NoError(t, err)
+		j.maxSize = 300
+
+		// the journal could not be moved over a directory, events are still appended to it
+		assert.NoError(t, os.Mkdir(path.Join(stateDir, journalFileName+".1"), 0750))
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, j.append(Event{Time: time.Now(), Type: EventExit, Service: "s1", PID: i}))
+		}
+		assert.NoError(t, j.close())
+
+		content, err := os.ReadFile(path.Join(stateDir, journalFileName))
+		assert.NoError(t, err)
+		assert.Equal(t, 10, strings.Count(string(content), "\n"))
+	})
+
+	t.Run("journaled_events", func(t *testing.T) {
+		stateDir := t.TempDir()
+		j, err := openJournal(stateDir)
+		assert.NoError(t, err)
+
+		bus := newEventBus()
+		bus.journal = j
+		bus.publish(Event{Time: time.Now(), Type: EventStatus, Service: "s1", NewStatus: Running})
+		bus.publish(Event{Time: time.Now(), Type: EventHealth, Service: "s1", Healthy: true})
+		bus.publish(Event{Time: time.Now(), Type: EventExit, Service: "s1", Exit: &ExitInfo{Code: 1}})
+		assert.NoError(t, j.close())
+
+		// all events are kept in memory, only status changes and exits are persisted
+		assert.Len(t, bus.recent("s1"), 3)
+		events, err := ReadJournal(stateDir, "s1", time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, events, 2)
+		assert.Equal(t, EventStatus, events[0].Type)
+		assert.Equal(t, EventExit, events[1].Type)
+	})
+
+	t.Run("concurrent_publish", func(t *testing.T) {
+		stateDir := t.TempDir()
+		j, err := openJournal(stateDir)
+		assert.NoError(t, err)
+
+		bus := newEventBus()
+		bus.journal = j
+		wg := sync.WaitGroup{}
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				for pid := 0; pid < 50; pid++ {
+					bus.publish(Event{Time: time.Now(), Type: EventExit, Service: fmt.Sprintf("s%d", i), PID: pid})
+				}
+			}(i)
+		}
+		wg.Wait()
+		assert.NoError(t, j.close())
+
+		// the journal has the events in the order they were published
+		published := bus.recent("")
+		events, err := ReadJournal(stateDir, "", time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, events, len(published))
+		for i := range events {
+			assert.Equal(t, published[i].Service, events[i].Service)
+			assert.Equal(t, published[i].PID, events[i].PID)
+		}
+	})
+
+	t.Run("no_journal", func(t *testing.T) {
+		events, err := ReadJournal(t.TempDir(), "", time.Time{})
+		assert.NoError(t, err)
+		assert.Empty(t, events)
+	})
+}
@@ -0,0 +1,74 @@
+package manager
+
+import (
+	"sync"
+)
+
+// maxLineLength is the maximum length of a log line, longer lines are split into multiple lines
+const maxLineLength = 16 * 1024
+
+// lineWriter splits the bytes written to it into lines, and calls emit with each line without its terminator.
+// lines are terminated by \n, \r\n, or a lone \r. partial lines are kept until they are terminated, or until Flush is called.
+type lineWriter struct {
+	emit   func(line string)
+	maxLen int
+	buf    []byte
+	// emitted is true if the current line was emitted before its terminator was written, after a \r or after reaching maxLen,
+	// so that a following terminator does not emit an empty line
+	emitted bool
+	mut     sync.Mutex
+}
+
+func newLineWriter(maxLen int, emit func(line string)) *lineWriter {
+	return &lineWriter{
+		emit:   emit,
+		maxLen: maxLen,
+	}
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	for _, b := range p {
+		switch b {
+		case '\n':
+			if !w.emitted {
+				w.emitLocked()
+			}
+			w.emitted = false
+
+		case '\r':
+			if !w.emitted {
+				w.emitLocked()
+			}
+			w.emitted = true
+
+		default:
+			w.emitted = false
+			w.buf = append(w.buf, b)
+			if len(w.buf) >= w.maxLen {
+				w.emitLocked()
+				w.emitted = true
+			}
+		}
+	}
+
+	return len(p), nil
+}
+
+// Flush emits the partial line kept by the writer, if any
+func (w *lineWriter) Flush() {
+	w.mut.Lock()
+	defer w.mut.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emitLocked()
+	}
+	w.emitted = false
+}
+
+func (w *lineWriter) emitLocked() {
+	w.emit(string(w.buf))
+	w.buf = w.buf[:0]
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLineWriter(t *testing.T) {
+	newWriter := func(maxLen int) (*lineWriter, *[]string) {
+		lines := []string{}
+		return newLineWriter(maxLen, func(line string) {
+			lines = append(lines, line)
+		}), &lines
+	}
+
+	t.Run("partial_and_multiple_lines", func(t *testing.T) {
+		w, lines := newWriter(maxLineLength)
+
+		_, err := w.Write([]byte("hel"))
+		assert.NoError(t, err)
+		assert.Empty(t, *lines)
+
+		_, err = w.Write([]byte("lo\nworld\n\nfoo"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"hello", "world", ""}, *lines)
+
+		w.Flush()
+		assert.Equal(t, []string{"hello", "world", "", "foo"}, *lines)
+	})
+
+	t.Run("empty_write", func(t *testing.T) {
+		w, lines := newWriter(maxLineLength)
+
+		n, err := w.Write([]byte{})
+		assert.NoError(t, err)
+		assert.Equal(t, 0, n)
+
+		w.Flush()
+		assert.Empty(t, *lines)
+	})
+
+	t.Run("carriage_return", func(t *testing.T) {
+		w, lines := newWriter(maxLineLength)
+
+		_, err := w.Write([]byte("windows\r"))
+		assert.NoError(t, err)
+		_, err = w.Write([]byte("\nprogress 1\rprogress 2\r\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"windows", "progress 1", "progress 2"}, *lines)
+	})
+
+	t.Run("long_line", func(t *testing.T) {
+		w, lines := newWriter(4)
+
+		_, err := w.Write([]byte(strings.Repeat("a", 10) + "\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"aaaa", "aaaa", "aa"}, *lines)
+
+		_, err = w.Write([]byte("bbbb\nc\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"aaaa", "aaaa", "aa", "bbbb", "c"}, *lines)
+	})
+}
@@ -6,18 +6,69 @@ import (
 	"os"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
 	"gopkg.in/yaml.v3"
 )
 
 type ServiceOptions struct {
-	Name        string `yaml:"omitempty"`
+	Name        string `yaml:"-"`
 	Cmd         string
 	Log         string
 	After       []string
 	OneShot     bool
 	HealthCheck string
+	// Reload is a command, or a signal name like SIGHUP, used to make the service re-read its configuration
+	Reload string
+	// Disabled services are known to sminit, but are never started until they are enabled
+	Disabled bool `yaml:"disabled,omitempty"`
+	// Schedule makes a oneshot service run periodically, instead of once when it is eligible to run
+	Schedule ScheduleOptions `yaml:"schedule,omitempty"`
+	// StartDelay is how long sminit waits after the service becomes eligible to run before starting it
+	StartDelay time.Duration `yaml:"start_delay,omitempty"`
+	// StartTimeout is how long the service has to become healthy after its process is started, before it is failed and restarted
+	StartTimeout time.Duration `yaml:"start_timeout,omitempty"`
+	// StopTimeout is how long the processes of the service have to terminate after SIGTERM when it is stopped, before they are killed
+	StopTimeout time.Duration `yaml:"stop_timeout,omitempty"`
+	// ExecStartPre are commands run before the process of the service is started, the process is not started if one of them fails
+	ExecStartPre []string `yaml:"exec_start_pre,omitempty"`
+	// ExecStartPost are commands run once the service is healthy
+	ExecStartPost []string `yaml:"exec_start_post,omitempty"`
+	// ExecStop are commands run when the service is stopped, before its process is killed
+	ExecStop []string `yaml:"exec_stop,omitempty"`
+	// ExecStopPost are commands run after the process of the service has terminated
+	ExecStopPost []string `yaml:"exec_stop_post,omitempty"`
+	// Resources are limits applied to the cgroup of the service
+	Resources ResourceOptions `yaml:"resources,omitempty"`
+	// Rlimits are resource limits applied to the process of the service
+	Rlimits RlimitOptions `yaml:"rlimits,omitempty"`
+	// Nice is the scheduling priority of the process of the service, between -20 and 19
+	Nice int `yaml:"nice,omitempty"`
+	// OOMScoreAdj adjusts how likely the process of the service is killed when memory runs out, between -1000 and 1000
+	OOMScoreAdj int `yaml:"oom_score_adj,omitempty"`
+	// CPUAffinity are the cpus the process of the service is allowed to run on
+	CPUAffinity []int `yaml:"cpu_affinity,omitempty"`
+	// IOPrio is the io scheduling class and level of the process of the service, like best-effort:4
+	IOPrio string `yaml:"ioprio,omitempty"`
+	// Conditions are checked before the service is started, the service is skipped if one of them is not met
+	Conditions ConditionOptions `yaml:"conditions,omitempty"`
+	// OnFailure are services started when this service fails, with its name and exit in their environment
+	OnFailure []string `yaml:"on_failure,omitempty"`
+	// OnSuccess are services started when this service terminates successfully, with its name and exit in their environment
+	OnSuccess []string `yaml:"on_success,omitempty"`
+	// HookTimeout is the maximum time each hook command is allowed to run, the default is 30s
+	HookTimeout time.Duration `yaml:"hook_timeout,omitempty"`
+
+	// LogLevel overrides sminit log level for the logs of the service
+	LogLevel string `yaml:"log_level,omitempty"`
+	// LogFile configures the log file of the service if Log is "file"
+	LogFile LogFileOptions `yaml:"log_file,omitempty"`
+	// LogCmd is the command of the log companion that receives the output of the service on its stdin if Log is "pipe"
+	LogCmd string `yaml:"log_cmd,omitempty"`
+	// Syslog configures how the output of the service is sent to syslog if Log is "syslog"
+	Syslog SyslogOptions `yaml:"syslog,omitempty"`
 }
 
 // LoadAll is responsible for loading all services from /etc/sminit into multiple Service structs
@@ -78,5 +129,68 @@ func ReadService(reader io.Reader, serviceName string) (ServiceOptions, error) {
 		return ServiceOptions{}, errors.Wrapf(err, "could not unmarshal bytes contents %s", (bytes))
 	}
 
+	if service.LogLevel != "" {
+		if _, err := zerolog.ParseLevel(service.LogLevel); err != nil {
+			return ServiceOptions{}, errors.Wrapf(err, "service %s has invalid log_level %s", serviceName, service.LogLevel)
+		}
+	}
+
+	switch service.Log {
+	case "", LogNull, LogStdout, LogFile, LogSyslog, LogPipe:
+	default:
+		return ServiceOptions{}, fmt.Errorf("service %s has invalid log %s, it should be stdout, file, syslog, pipe or null", serviceName, service.Log)
+	}
+
+	if service.Log == LogFile && service.LogFile.Path == "" {
+		return ServiceOptions{}, fmt.Errorf("service %s log is file, but log_file path is not set", serviceName)
+	}
+
+	if service.Log == LogPipe && service.LogCmd == "" {
+		return ServiceOptions{}, fmt.Errorf("service %s log is pipe, but log_cmd is not set", serviceName)
+	}
+
+	if service.StartDelay < 0 || service.StartTimeout < 0 || service.StopTimeout < 0 {
+		return ServiceOptions{}, fmt.Errorf("service %s start_delay, start_timeout and stop_timeout should not be negative", serviceName)
+	}
+
+	for _, name := range append(append([]string{}, service.OnFailure...), service.OnSuccess...) {
+		if name == serviceName {
+			return ServiceOptions{}, fmt.Errorf("service %s should not start itself on failure or success", serviceName)
+		}
+	}
+
+	if err := service.Resources.validate(); err != nil {
+		return ServiceOptions{}, errors.Wrapf(err, "service %s has invalid resources", serviceName)
+	}
+
+	if err := service.validateTunables(); err != nil {
+		return ServiceOptions{}, errors.Wrapf(err, "service %s has invalid process options", serviceName)
+	}
+
+	if service.HookTimeout < 0 {
+		return ServiceOptions{}, fmt.Errorf("service %s hook_timeout should not be negative", serviceName)
+	}
+
+	if isReloadSignal(service.Reload) {
+		if _, err := ParseSignal(service.Reload); err != nil {
+			return ServiceOptions{}, fmt.Errorf("service %s has invalid reload signal %s", serviceName, service.Reload)
+		}
+	}
+
+	if service.Schedule.IsSet() {
+		if !service.OneShot {
+			return ServiceOptions{}, fmt.Errorf("service %s has a schedule, but it is not oneshot", serviceName)
+		}
+		if err := service.Schedule.validate(); err != nil {
+			return ServiceOptions{}, errors.Wrapf(err, "service %s has invalid schedule", serviceName)
+		}
+	}
+
+	if service.Log == LogSyslog {
+		if err := service.Syslog.validate(); err != nil {
+			return ServiceOptions{}, errors.Wrapf(err, "service %s has invalid syslog options", serviceName)
+		}
+	}
+
 	return service, nil
 }
@@ -6,6 +6,7 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
@@ -23,7 +24,7 @@ func TestLoader(t *testing.T) {
 				After:       []string{"s2", "s3"},
 				OneShot:     true,
 				HealthCheck: "sleep 5",
-				Log:         "log1",
+				Log:         "stdout",
 			},
 			"s2": {
 				Name:        "s2",
@@ -31,14 +32,14 @@ func TestLoader(t *testing.T) {
 				After:       []string{"s3"},
 				OneShot:     true,
 				HealthCheck: "sleep 5",
-				Log:         "log2",
+				Log:         "null",
 			},
 			"s3": {
 				Name:    "s3",
 				Cmd:     "echo hi",
 				After:   []string{"s2", "s3"},
 				OneShot: true,
-				Log:     "log1",
+				Log:     "stdout",
 			},
 			"s4": {
 				Name:        "s4",
@@ -46,7 +47,7 @@ func TestLoader(t *testing.T) {
 				After:       []string{"s2", "s3"},
 				OneShot:     false,
 				HealthCheck: "sleep 5",
-				Log:         "log1",
+				Log:         "stdout",
 			},
 		}
 		err := WriteServices(tmpDir, want)
@@ -65,7 +66,7 @@ func TestLoader(t *testing.T) {
 				After:       []string{"s2", "s3"},
 				OneShot:     true,
 				HealthCheck: "sleep 5",
-				Log:         "log1",
+				Log:         "stdout",
 			},
 		}
 
@@ -76,6 +77,44 @@ func TestLoader(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, want["s1"], serviceOptions)
 	})
+
+	t.Run("log", func(t *testing.T) {
+		// null and an unset log both discard the output
+		serviceOptions, err := ReadService(strings.NewReader("cmd: server\nlog: null\n"), "s1")
+		assert.NoError(t, err)
+		assert.Equal(t, "", serviceOptions.Log)
+
+		_, err = ReadService(strings.NewReader("cmd: server\nlog: flie\n"), "s1")
+		assert.Error(t, err)
+	})
+
+	t.Run("reload", func(t *testing.T) {
+		serviceOptions, err := ReadService(strings.NewReader("cmd: server\nreload: SIGHUP\n"), "s1")
+		assert.NoError(t, err)
+		assert.Equal(t, "SIGHUP", serviceOptions.Reload)
+
+		_, err = ReadService(strings.NewReader("cmd: server\nreload: SIGFOO\n"), "s1")
+		assert.Error(t, err)
+	})
+
+	t.Run("schedule", func(t *testing.T) {
+		serviceOptions, err := ReadService(strings.NewReader("cmd: backup\noneshot: true\nschedule: \"@daily\"\n"), "s1")
+		assert.NoError(t, err)
+		assert.Equal(t, ScheduleOptions{Cron: "@daily"}, serviceOptions.Schedule)
+
+		serviceOptions, err = ReadService(strings.NewReader("cmd: backup\noneshot: true\nschedule:\n  every: 15m\n  jitter: 1m\n  catch_up: true\n"), "s1")
+		assert.NoError(t, err)
+		assert.Equal(t, ScheduleOptions{Every: 15 * time.Minute, Jitter: time.Minute, CatchUp: true}, serviceOptions.Schedule)
+
+		_, err = ReadService(strings.NewReader("cmd: backup\nschedule: \"@daily\"\n"), "s1")
+		assert.Error(t, err)
+
+		_, err = ReadService(strings.NewReader("cmd: backup\noneshot: true\nschedule: \"61 * * * *\"\n"), "s1")
+		assert.Error(t, err)
+
+		_, err = ReadService(strings.NewReader("cmd: backup\noneshot: true\nschedule:\n  cron: \"@daily\"\n  every: 1h\n"), "s1")
+		assert.Error(t, err)
+	})
 }
 
 func WriteServices(dir string, serviceOptionsMap map[string]ServiceOptions) error {
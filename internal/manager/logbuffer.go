@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// StreamStdout is the stream of lines written by a service process to its stdout
+	StreamStdout = "stdout"
+	// StreamStderr is the stream of lines written by a service process to its stderr
+	StreamStderr = "stderr"
+)
+
+const (
+	// maxLogLines is the number of log lines kept in memory for each service
+	maxLogLines = 1000
+	// subscriberBufferSize is the number of lines buffered for each follower, lines are dropped for followers that fall behind
+	subscriberBufferSize = 256
+)
+
+// LogLine is a line written by a service process
+type LogLine struct {
+	Time   time.Time
+	Stream string
+	Line   string
+}
+
+// logBuffer is a bounded ring buffer of the recent log lines of a service, that could be followed
+type logBuffer struct {
+	lines []LogLine
+	// start is the index of the oldest line in lines
+	start int
+	count int
+
+	subscribers map[chan LogLine]bool
+	mut         sync.Mutex
+}
+
+func newLogBuffer(size int) *logBuffer {
+	return &logBuffer{
+		lines:       make([]LogLine, size),
+		subscribers: map[chan LogLine]bool{},
+	}
+}
+
+func (b *logBuffer) add(line LogLine) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if b.count < len(b.lines) {
+		b.lines[(b.start+b.count)%len(b.lines)] = line
+		b.count++
+	} else {
+		b.lines[b.start] = line
+		b.start = (b.start + 1) % len(b.lines)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- line:
+		default:
+		}
+	}
+}
+
+// last returns the last tail lines written after since. all lines are returned if tail is negative.
+func (b *logBuffer) last(tail int, since time.Time) []LogLine {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	return b.lastLocked(tail, since)
+}
+
+func (b *logBuffer) lastLocked(tail int, since time.Time) []LogLine {
+	ret := []LogLine{}
+	for i := 0; i < b.count; i++ {
+		line := b.lines[(b.start+i)%len(b.lines)]
+		if line.Time.Before(since) {
+			continue
+		}
+		ret = append(ret, line)
+	}
+
+	if tail >= 0 && tail < len(ret) {
+		ret = ret[len(ret)-tail:]
+	}
+	return ret
+}
+
+// follow returns the last tail lines written after since, and a channel that receives every line added afterwards.
+// cancel should be called to stop receiving lines.
+func (b *logBuffer) follow(tail int, since time.Time) (lines []LogLine, ch <-chan LogLine, cancel func()) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	sub := make(chan LogLine, subscriberBufferSize)
+	b.subscribers[sub] = true
+
+	cancel = func() {
+		b.mut.Lock()
+		defer b.mut.Unlock()
+		delete(b.subscribers, sub)
+	}
+
+	return b.lastLocked(tail, since), sub, cancel
+}
@@ -0,0 +1,49 @@
+package manager
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogBuffer(t *testing.T) {
+	t.Run("ring", func(t *testing.T) {
+		buffer := newLogBuffer(3)
+		start := time.Now()
+		for i := 0; i < 5; i++ {
+			buffer.add(LogLine{Time: start.Add(time.Duration(i) * time.Second), Stream: StreamStdout, Line: fmt.Sprint(i)})
+		}
+
+		assert.Equal(t, []string{"2", "3", "4"}, lineTexts(buffer.last(-1, time.Time{})))
+		assert.Equal(t, []string{"3", "4"}, lineTexts(buffer.last(2, time.Time{})))
+		assert.Equal(t, []string{"4"}, lineTexts(buffer.last(-1, start.Add(4*time.Second))))
+		assert.Equal(t, []string{}, lineTexts(buffer.last(0, time.Time{})))
+	})
+
+	t.Run("follow", func(t *testing.T) {
+		buffer := newLogBuffer(3)
+		buffer.add(LogLine{Time: time.Now(), Stream: StreamStdout, Line: "old"})
+
+		lines, ch, cancel := buffer.follow(-1, time.Time{})
+		assert.Equal(t, []string{"old"}, lineTexts(lines))
+
+		buffer.add(LogLine{Time: time.Now(), Stream: StreamStderr, Line: "new"})
+		line := <-ch
+		assert.Equal(t, "new", line.Line)
+		assert.Equal(t, StreamStderr, line.Stream)
+
+		cancel()
+		buffer.add(LogLine{Time: time.Now(), Stream: StreamStdout, Line: "ignored"})
+		assert.Len(t, ch, 0)
+	})
+}
+
+func lineTexts(lines []LogLine) []string {
+	ret := []string{}
+	for _, line := range lines {
+		ret = append(ret, line.Line)
+	}
+	return ret
+}
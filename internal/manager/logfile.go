@@ -0,0 +1,298 @@
+package manager
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// LogStdout dumps service output with sminit logs
+	LogStdout = "stdout"
+	// LogFile writes service output to a log file rotated by sminit
+	LogFile = "file"
+	// LogNull discards service output
+	LogNull = "null"
+)
+
+const (
+	// defaultMaxFiles is the number of rotated log files kept if no retention count is configured
+	defaultMaxFiles = 5
+	// rotateRetryInterval is the time to wait before retrying a failed rotation
+	rotateRetryInterval = time.Minute
+)
+
+// Size is a number of bytes, it could be written in yaml as a number, or with a K, M or G suffix
+type Size int64
+
+// LogFileOptions configures the log file of a service
+type LogFileOptions struct {
+	Path string
+	// MaxSize is the size after which the log file is rotated, 0 disables size based rotation
+	MaxSize Size `yaml:"max_size,omitempty"`
+	// MaxAge is the age after which the log file is rotated, 0 disables age based rotation
+	MaxAge time.Duration `yaml:"max_age,omitempty"`
+	// MaxFiles is the number of rotated log files to keep
+	MaxFiles int `yaml:"max_files,omitempty"`
+	// Compress compresses rotated log files with gzip
+	Compress bool `yaml:"compress,omitempty"`
+}
+
+// rotatingFile is a writer to a log file that is rotated based on its size and age
+type rotatingFile struct {
+	opts LogFileOptions
+	file *os.File
+	size int64
+	// createdAt is the time the current log file was started, max_age is counted from it
+	createdAt time.Time
+	// retryAt is the time before which a failed rotation is not retried
+	retryAt time.Time
+	closed  bool
+	mut     sync.Mutex
+}
+
+// UnmarshalYAML parses sizes like 1024, 512K, 10M, 10MB or 1G
+func (s *Size) UnmarshalYAML(value *yaml.Node) error {
+	size, err := ParseSize(value.Value)
+	if err != nil {
+		return err
+	}
+	*s = size
+	return nil
+}
+
+// ParseSize parses sizes like 1024, 512K, 10M, 10MB or 1G
+func ParseSize(str string) (Size, error) {
+	str = strings.TrimSuffix(strings.ToUpper(strings.TrimSpace(str)), "B")
+
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(str, "K"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(str, "M"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(str, "G"):
+		multiplier = 1 << 30
+	}
+	if multiplier != 1 {
+		str = str[:len(str)-1]
+	}
+
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %s", str)
+	}
+	return Size(n * multiplier), nil
+}
+
+func newRotatingFile(opts LogFileOptions) (*rotatingFile, error) {
+	if opts.MaxFiles <= 0 {
+		opts.MaxFiles = defaultMaxFiles
+	}
+
+	err := os.MkdirAll(filepath.Dir(opts.Path), 0755)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create directory of log file %s", opts.Path)
+	}
+
+	r := rotatingFile{
+		opts: opts,
+	}
+	err = r.open()
+	if err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (r *rotatingFile) open() error {
+	file, err := os.OpenFile(r.opts.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return errors.Wrapf(err, "could not open log file %s", r.opts.Path)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return errors.Wrapf(err, "could not stat log file %s", r.opts.Path)
+	}
+
+	r.file = file
+	r.size = info.Size()
+	r.createdAt = r.fileCreatedAt(info)
+	return nil
+}
+
+// fileCreatedAt returns the time the log file was started, so its age is kept when it is reopened by a restarted service.
+// it is the birth time of the file, or the last write to the previous rotated file if the file system does not record it.
+func (r *rotatingFile) fileCreatedAt(info os.FileInfo) time.Time {
+	if info.Size() == 0 {
+		return time.Now()
+	}
+
+	stat := unix.Statx_t{}
+	err := unix.Statx(unix.AT_FDCWD, r.opts.Path, 0, unix.STATX_BTIME, &stat)
+	if err == nil && stat.Mask&unix.STATX_BTIME != 0 {
+		return time.Unix(stat.Btime.Sec, int64(stat.Btime.Nsec))
+	}
+
+	for _, rotated := range []string{r.rotatedPath(1), fmt.Sprintf("%s.1", r.opts.Path)} {
+		if rotatedInfo, err := os.Stat(rotated); err == nil {
+			return rotatedInfo.ModTime()
+		}
+	}
+	return info.ModTime()
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	if r.closed {
+		return 0, errors.Errorf("log file %s is closed", r.opts.Path)
+	}
+
+	// the log file is reopened if it could not be reopened after a rotation
+	if r.file == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if r.shouldRotate(len(p)) {
+		err := r.rotate()
+		if err != nil {
+			SminitLog.Error().Msgf("failed to rotate log file %s. %s", r.opts.Path, err.Error())
+			r.retryAt = time.Now().Add(rotateRetryInterval)
+		}
+		if r.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file
+func (r *rotatingFile) Close() error {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+
+	r.closed = true
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *rotatingFile) shouldRotate(writeLen int) bool {
+	if r.size == 0 || time.Now().Before(r.retryAt) {
+		return false
+	}
+	if r.opts.MaxSize > 0 && r.size+int64(writeLen) > int64(r.opts.MaxSize) {
+		return true
+	}
+	return r.opts.MaxAge > 0 && time.Since(r.createdAt) > r.opts.MaxAge
+}
+
+// rotate renames the log file to <path>.1, shifting older rotated files, and removing those exceeding the retention count.
+// the log file is reopened even if the rotation fails, so output is still written to it.
+func (r *rotatingFile) rotate() error {
+	rotated := fmt.Sprintf("%s.1", r.opts.Path)
+	if r.opts.Compress {
+		// a rotated file that could not be compressed before would be overwritten by this rotation
+		if _, err := os.Stat(rotated); err == nil {
+			if err := compressFile(rotated); err != nil {
+				return errors.Wrapf(err, "could not compress rotated log file %s", rotated)
+			}
+		}
+	}
+
+	err := r.file.Close()
+	r.file = nil
+	if err != nil {
+		SminitLog.Error().Msgf("failed to close log file %s. %s", r.opts.Path, err.Error())
+	}
+
+	rotateErr := r.shift(rotated)
+	if err := r.open(); err != nil {
+		return err
+	}
+	return rotateErr
+}
+
+// shift renames the log file to rotated, after shifting older rotated files
+func (r *rotatingFile) shift(rotated string) error {
+	_ = os.Remove(r.rotatedPath(r.opts.MaxFiles))
+	for i := r.opts.MaxFiles - 1; i >= 1; i-- {
+		err := os.Rename(r.rotatedPath(i), r.rotatedPath(i+1))
+		if err != nil && !os.IsNotExist(err) {
+			return errors.Wrapf(err, "could not rename rotated log file %s", r.rotatedPath(i))
+		}
+	}
+
+	err := os.Rename(r.opts.Path, rotated)
+	if err != nil {
+		return errors.Wrapf(err, "could not rename log file %s", r.opts.Path)
+	}
+
+	if r.opts.Compress {
+		err = compressFile(rotated)
+		if err != nil {
+			SminitLog.Error().Msgf("failed to compress rotated log file %s, it is compressed on the next rotation. %s", rotated, err.Error())
+		}
+	}
+	return nil
+}
+
+// rotatedPath returns the path of the rotated log file with index i
+func (r *rotatingFile) rotatedPath(i int) string {
+	if r.opts.Compress {
+		return fmt.Sprintf("%s.%d.gz", r.opts.Path, i)
+	}
+	return fmt.Sprintf("%s.%d", r.opts.Path, i)
+}
+
+// compressFile compresses the file at path to path.gz, and removes it. path.gz is removed if it could not be written.
+func compressFile(path string) (err error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.OpenFile(path+".gz", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0640)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		dst.Close()
+		if err != nil {
+			os.Remove(path + ".gz")
+		}
+	}()
+
+	gz := gzip.NewWriter(dst)
+	if _, err := io.Copy(gz, src); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
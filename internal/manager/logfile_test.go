@@ -0,0 +1,124 @@
+package manager
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogFile(t *testing.T) {
+	t.Run("parse_size", func(t *testing.T) {
+		sizes := map[string]Size{
+			"1024": 1024,
+			"512K": 512 << 10,
+			"10M":  10 << 20,
+			"10mb": 10 << 20,
+			"1G":   1 << 30,
+		}
+		for str, want := range sizes {
+			size, err := ParseSize(str)
+			assert.NoError(t, err)
+			assert.Equal(t, want, size, str)
+		}
+
+		_, err := ParseSize("ten")
+		assert.Error(t, err)
+	})
+
+	t.Run("rotate_by_size", func(t *testing.T) {
+		logPath := path.Join(t.TempDir(), "service.log")
+		file, err := newRotatingFile(LogFileOptions{
+			Path:     logPath,
+			MaxSize:  10,
+			MaxFiles: 2,
+		})
+		assert.NoError(t, err)
+
+		for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "line 4\n"} {
+			_, err = file.Write([]byte(line))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, file.Close())
+
+		assertFileContent(t, logPath, "line 4\n")
+		assertFileContent(t, logPath+".1", "line 3\n")
+		assertFileContent(t, logPath+".2", "line 2\n")
+		assert.NoFileExists(t, logPath+".3")
+	})
+
+	t.Run("rotate_compressed", func(t *testing.T) {
+		logPath := path.Join(t.TempDir(), "service.log")
+		file, err := newRotatingFile(LogFileOptions{
+			Path:     logPath,
+			MaxSize:  10,
+			Compress: true,
+		})
+		assert.NoError(t, err)
+
+		for _, line := range []string{"line 1\n", "line 2\n"} {
+			_, err = file.Write([]byte(line))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, file.Close())
+
+		assertFileContent(t, logPath, "line 2\n")
+		assert.FileExists(t, logPath+".1.gz")
+		assert.NoFileExists(t, logPath+".1")
+	})
+
+	t.Run("rotate_by_age_after_reopen", func(t *testing.T) {
+		logPath := path.Join(t.TempDir(), "service.log")
+		opts := LogFileOptions{Path: logPath, MaxAge: 50 * time.Millisecond}
+
+		// the age of the log file is kept when a restarted service reopens it
+		file, err := newRotatingFile(opts)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte("line 1\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+
+		time.Sleep(100 * time.Millisecond)
+
+		file, err = newRotatingFile(opts)
+		assert.NoError(t, err)
+		_, err = file.Write([]byte("line 2\n"))
+		assert.NoError(t, err)
+		assert.NoError(t, file.Close())
+
+		assertFileContent(t, logPath, "line 2\n")
+		assertFileContent(t, logPath+".1", "line 1\n")
+	})
+
+	t.Run("rotate_failure", func(t *testing.T) {
+		logPath := path.Join(t.TempDir(), "service.log")
+		file, err := newRotatingFile(LogFileOptions{
+			Path:     logPath,
+			MaxSize:  10,
+			Compress: true,
+		})
+		assert.NoError(t, err)
+
+		// a rotated file left uncompressed is compressed on the next rotation instead of being overwritten
+		assert.NoError(t, os.WriteFile(logPath+".1", []byte("line 0\n"), 0640))
+
+		for _, line := range []string{"line 1\n", "line 2\n"} {
+			_, err = file.Write([]byte(line))
+			assert.NoError(t, err)
+		}
+		assert.NoError(t, file.Close())
+
+		assertFileContent(t, logPath, "line 2\n")
+		assert.FileExists(t, logPath+".1.gz")
+		assert.FileExists(t, logPath+".2.gz")
+		assert.NoFileExists(t, logPath+".1")
+	})
+}
+
+func assertFileContent(t *testing.T, path string, want string) {
+	content, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, want, string(content))
+}
@@ -0,0 +1,288 @@
+package manager
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cenkalti/backoff"
+	"github.com/pkg/errors"
+)
+
+// LogPipe feeds service output to the stdin of a companion process started by sminit
+const LogPipe = "pipe"
+
+const (
+	// companionStopTimeout is the time a log companion is given to drain its input before it is killed
+	companionStopTimeout = 5 * time.Second
+	// companionQueueSize is the number of lines queued for a log companion, lines are dropped while the queue is full
+	companionQueueSize = 1024
+)
+
+// logCompanion is a process that receives the output of a service on its stdin.
+// it is restarted whenever it terminates, and it outlives the processes of the service,
+// so lines written while it is restarting are kept in the queue and the pipe until it reads them.
+// a companion that does not read its input makes lines be dropped, it never blocks the output of the service.
+type logCompanion struct {
+	name   string
+	cmdStr string
+
+	// reader is the stdin of the companion process, writer receives the service output
+	reader *os.File
+	writer *os.File
+
+	// lines are queued by write, and written to writer by feed
+	lines    chan string
+	dropped  int
+	stopping chan struct{}
+	fed      chan struct{}
+
+	status   Status
+	pid      int
+	restarts int
+	lastExit *ExitInfo
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	mut    sync.RWMutex
+}
+
+// logCompanion returns the log companion of the service, starting it if it is not running
+func (s *Service) logCompanion() (*logCompanion, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.companion != nil {
+		return s.companion, nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create log pipe")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	companion := &logCompanion{
+		name:     s.Name + "/log",
+		cmdStr:   s.options.LogCmd,
+		reader:   reader,
+		writer:   writer,
+		lines:    make(chan string, companionQueueSize),
+		stopping: make(chan struct{}),
+		fed:      make(chan struct{}),
+		status:   Pending,
+		cancel:   cancel,
+		done:     make(chan struct{}),
+	}
+	s.companion = companion
+
+	go companion.run(ctx)
+	go companion.feed()
+
+	return companion, nil
+}
+
+// stopLogCompanion stops the log companion of the service, if any, after giving it time to read the remaining output
+func (s *Service) stopLogCompanion() {
+	s.mut.Lock()
+	companion := s.companion
+	s.companion = nil
+	s.mut.Unlock()
+
+	if companion != nil {
+		companion.stop()
+	}
+}
+
+func (c *logCompanion) run(ctx context.Context) {
+	defer close(c.done)
+
+	attempts := 0
+	err := backoff.Retry(func() error {
+		if ctx.Err() != nil {
+			return backoff.Permanent(errors.Errorf("log companion %s was stopped", c.name))
+		}
+
+		attempts++
+		if attempts > 1 {
+			c.mut.Lock()
+			c.restarts++
+			c.mut.Unlock()
+		}
+
+		splittedCmd := strings.Split(c.cmdStr, " ")
+		cmd := exec.Command(splittedCmd[0], splittedCmd[1:]...)
+		cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+		cmd.Stdin = c.reader
+		output := newLineWriter(maxLineLength, func(line string) {
+			SminitLog.Info().Str("service", c.name).Msg(line)
+		})
+		cmd.Stdout = output
+		cmd.Stderr = output
+
+		err := cmd.Start()
+		if err != nil {
+			c.setStatus(Failed, 0)
+			SminitLog.Error().Msgf("error while starting log companion %s. %s", c.name, err.Error())
+			return errors.New("restarting log companion")
+		}
+		c.setStatus(Running, cmd.Process.Pid)
+
+		exited := make(chan error, 1)
+		go func() {
+			exited <- cmd.Wait()
+		}()
+
+		select {
+		case err = <-exited:
+		case <-ctx.Done():
+			// the writer is closed by stop before it cancels ctx, so the companion gets EOF once it has read all remaining lines
+			select {
+			case err = <-exited:
+			case <-time.After(companionStopTimeout):
+				_ = cmd.Process.Kill()
+				err = <-exited
+			}
+		}
+		output.Flush()
+		c.recordExit(cmd.ProcessState)
+
+		if ctx.Err() != nil {
+			return backoff.Permanent(errors.Errorf("log companion %s was stopped", c.name))
+		}
+		if err != nil {
+			c.setStatus(Failed, 0)
+			SminitLog.Error().Msgf("log companion %s exited. %s", c.name, err.Error())
+		} else {
+			c.setStatus(Successful, 0)
+		}
+		return errors.New("restarting log companion")
+	}, backoff.WithContext(newExponentialBackOff(), ctx))
+
+	c.setStatus(Stopped, 0)
+	SminitLog.Info().Msg(err.Error())
+}
+
+// write queues the line for the companion, the line is dropped if the queue is full
+func (c *logCompanion) write(stream, line string) {
+	select {
+	case c.lines <- line:
+	default:
+		c.mut.Lock()
+		c.dropped++
+		dropped := c.dropped
+		c.mut.Unlock()
+		if dropped == 1 {
+			SminitLog.Warn().Msgf("log companion %s is not reading its input, dropping lines", c.name)
+		}
+	}
+}
+
+// feed writes the queued lines to the companion, until the companion is stopped and the queue is drained
+func (c *logCompanion) feed() {
+	defer close(c.fed)
+
+	for {
+		select {
+		case line := <-c.lines:
+			c.writeLine(line)
+		case <-c.stopping:
+			for {
+				select {
+				case line := <-c.lines:
+					c.writeLine(line)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+func (c *logCompanion) writeLine(line string) {
+	c.mut.Lock()
+	dropped := c.dropped
+	c.dropped = 0
+	c.mut.Unlock()
+	if dropped > 0 {
+		SminitLog.Warn().Msgf("log companion %s dropped %d lines", c.name, dropped)
+	}
+
+	_, err := c.writer.Write([]byte(line + "\n"))
+	if err != nil {
+		SminitLog.Error().Msgf("failed to write to log companion %s. %s", c.name, err.Error())
+	}
+}
+
+func (c *logCompanion) stop() {
+	// the queued lines are written before the companion gets EOF, unless it does not read them in time
+	close(c.stopping)
+	drained := true
+	select {
+	case <-c.fed:
+	case <-time.After(companionStopTimeout):
+		drained = false
+	}
+	// closing the writer unblocks feed if the companion is not reading
+	_ = c.writer.Close()
+	<-c.fed
+
+	c.cancel()
+	if !drained {
+		// the companion is not reading its input, so it is not given more time to drain it
+		c.mut.RLock()
+		pid := c.pid
+		c.mut.RUnlock()
+		if pid != 0 {
+			_ = syscall.Kill(pid, syscall.SIGKILL)
+		}
+	}
+	<-c.done
+	_ = c.reader.Close()
+}
+
+func (c *logCompanion) setStatus(status Status, pid int) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.status = status
+	c.pid = pid
+}
+
+func (c *logCompanion) recordExit(state *os.ProcessState) {
+	if state == nil {
+		return
+	}
+
+	status, ok := state.Sys().(syscall.WaitStatus)
+	if !ok {
+		return
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.lastExit = exitInfo(status)
+	c.pid = 0
+}
+
+// desc describes the log companion as a service linked to the service it receives output from
+func (c *logCompanion) desc(serviceName string) ServiceDesc {
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	desc := ServiceDesc{
+		Name:     c.name,
+		Status:   c.status,
+		PID:      c.pid,
+		Restarts: c.restarts,
+		LinkedTo: serviceName,
+	}
+	if c.lastExit != nil {
+		exit := *c.lastExit
+		desc.LastExit = &exit
+	}
+	return desc
+}
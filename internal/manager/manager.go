@@ -4,6 +4,7 @@ import (
 	"context"
 	"strings"
 	"sync"
+	"syscall"
 
 	"fmt"
 	"os/exec"
@@ -11,24 +12,28 @@ import (
 
 	"github.com/cenkalti/backoff"
 	"github.com/pkg/errors"
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/unix"
 )
 
 var (
 	ErrSminitInternalError = errors.New("sminit internal error")
 	ErrBadRequest          = errors.New("bad request")
+	ErrReloadFailed        = errors.New("reload failed")
 )
 
 // Manager handles service manipulation
 type Manager struct {
 	services map[string]*Service
 	mut      sync.RWMutex
-}
-
-type stdoutLogger struct {
-	serviceName string
-}
-type stderrLogger struct {
-	serviceName string
+	// ops serializes user requests that start or stop services
+	ops sync.Mutex
+	// events keeps recent events of all services
+	events *eventBus
+	// state keeps the intent of the user across sminit restarts, it is nil unless state is persisted
+	state *stateStore
+	// cgroupRoot is the cgroup the cgroups of services are created in, it is empty unless services are placed in cgroups
+	cgroupRoot string
 }
 
 // Status presents service status
@@ -47,8 +52,39 @@ const (
 	Pending Status = "pending"
 	// service is stopped by user, should not be started unless user requested
 	Stopped Status = "stopped"
+	// service is disabled by user or by its definition, it is never started until it is enabled
+	Disabled Status = "disabled"
+	// service was not started because one of its conditions is not met, it is considered healthy by its dependents
+	Skipped Status = "skipped"
+)
+
+// Reason describes why a service has failed, or was skipped
+type Reason string
+
+const (
+	// service process could not be started
+	ReasonStartError Reason = "start-error"
+	// service health check did not succeed in time
+	ReasonHealthTimeout Reason = "health-timeout"
+	// service did not become healthy within its start timeout
+	ReasonStartTimeout Reason = "start-timeout"
+	// an exec_start_pre hook of the service failed, so its process was not started
+	ReasonStartPreFailed Reason = "start-pre-failed"
+	// a condition of the service is not met, so it was skipped
+	ReasonConditionFailed Reason = "condition-failed"
+	// service process terminated with exit status other than 0, or was killed by a signal
+	ReasonNonZeroExit Reason = "non-zero-exit"
 )
 
+// ExitInfo describes how the last process of a service has terminated
+type ExitInfo struct {
+	// Code is the exit code of the process, -1 if it was killed by a signal
+	Code int
+	// Signal is the name of the signal that killed the process, if any
+	Signal string
+	Time   time.Time
+}
+
 // Service contains all needed information during the lifetime of a service
 type Service struct {
 	Name   string
@@ -62,8 +98,51 @@ type Service struct {
 	healthCheck string
 	oneShot     bool
 	cmdStr      string
-	stdout      stdoutLogger
-	stderr      stderrLogger
+	logs        *logBuffer
+	// options is the definition the service was created from, with defaults applied
+	options ServiceOptions
+
+	// pid is the process id of the running process of the service, 0 if there is none
+	pid int
+	// process is the running process of the service, nil if there is none
+	process *serviceProcess
+	// nextRun and lastRun are the times of the next and last runs of a scheduled service
+	nextRun time.Time
+	lastRun time.Time
+	// runDue is true if a run of a scheduled service is due, scheduled services are only started when a run is due
+	runDue bool
+	// cancelSchedule stops the timer of a scheduled service, it is nil if the service is not scheduled
+	cancelSchedule context.CancelFunc
+	// cgroup is the path of the cgroup the processes of the service are placed in, it is empty if there is none
+	cgroup string
+	// triggerEnv is added to the environment of the next run of the service, when it is started by a service it is on_failure or on_success of
+	triggerEnv []string
+	// adopted is the process of the service handed over by the sminit instance that re-executed into this one, until it is tracked by runService
+	adopted *adoptedService
+	// startedAt is the time the running process of the service was started
+	startedAt time.Time
+	// lastExit is nil until a process of the service terminates
+	lastExit *ExitInfo
+	// failureReason is the reason of the last failure of the service
+	failureReason Reason
+	// skipReason describes the condition that was not met when the service was last skipped
+	skipReason string
+	// restarts is the number of times the service process was restarted by sminit
+	restarts int
+	// lastChange is the time of the last status change
+	lastChange time.Time
+	// lastReload is nil until the service is reloaded
+	lastReload *ReloadInfo
+	// healthCheckDuration is the duration of the last health check of the service
+	healthCheckDuration time.Duration
+	// healthCheckFailures is the number of failed runs of the health check command
+	healthCheckFailures int
+	// companion is the log companion of the service if its log is "pipe", it is started on first use
+	companion *logCompanion
+	// events receives the status changes of the service, it is nil until the service is added to a manager
+	events *eventBus
+	// daemonLogger is the logger of the service when sminit logs are copied to syslog, it is created on first use
+	daemonLogger *zerolog.Logger
 
 	startSignal  chan bool
 	deleteSignal chan bool
@@ -74,15 +153,52 @@ type Service struct {
 	mut       sync.RWMutex
 }
 
+// ServiceDesc describes the current state of a service
 type ServiceDesc struct {
-	Name   string
-	Status Status
+	Name          string
+	Status        Status
+	PID           int
+	StartedAt     time.Time
+	LastExit      *ExitInfo
+	FailureReason Reason
+	// SkipReason describes the condition that was not met if the service is skipped
+	SkipReason string
+	Restarts   int
+	LastChange time.Time
+	LastReload *ReloadInfo
+	// HealthCheckDuration is the duration of the last health check, from the start of the process until it was found healthy or not
+	HealthCheckDuration time.Duration
+	// HealthCheckFailures is the number of failed runs of the health check command
+	HealthCheckFailures int
+	// NextRun and LastRun are the times of the next and last runs of a scheduled service, they are zero if there is none
+	NextRun time.Time
+	LastRun time.Time
+	// LinkedTo is the name of the service a log companion receives output from, it is empty for services
+	LinkedTo string
+	// Cgroup is the path of the cgroup of the service, it is empty if the service is not placed in a cgroup
+	Cgroup string
+}
+
+// ServiceDetails describes a service, its definition, its dependencies and its recent logs
+type ServiceDetails struct {
+	ServiceDesc `yaml:",inline"`
+	Definition  ServiceOptions
+	// Uptime is the time since the running process of the service was started
+	Uptime time.Duration
+	// Parents maps each service this service depends on to its status
+	Parents map[string]Status
+	// Children maps each service that depends on this service to its status
+	Children map[string]Status
+	Logs     []LogLine
+	// Resources is the resource accounting of the cgroup of the service, it is nil if it could not be read
+	Resources *ResourceUsage
 }
 
 // NewManager creates a new Manager struct and populates it with services generated from provided serviceOptions
 func NewManager(serviceOptions map[string]ServiceOptions) (*Manager, error) {
 	manager := Manager{
 		services: make(map[string]*Service),
+		events:   newEventBus(),
 	}
 
 	err := manager.populateServices(serviceOptions)
@@ -113,6 +229,7 @@ func (m *Manager) addService(service *Service) error {
 	if _, ok := m.services[service.Name]; ok {
 		return fmt.Errorf("service with the same name (%s) exists", service.Name)
 	}
+	service.events = m.events
 	m.services[service.Name] = service
 	return nil
 }
@@ -146,10 +263,11 @@ func (m *Manager) populateServices(serviceOptions map[string]ServiceOptions) err
 func (m *Manager) fireServices() {
 	for name, service := range m.services {
 		go m.serviceRoutine(name)
-		if m.isEligibleToRun(name) {
+		if m.isEligibleToRun(name) || service.hasAdopted() {
 			service.startSignal <- true
 		}
 	}
+	m.startSchedules()
 }
 
 // Add adds a new service to the list of services tracked by the manager
@@ -176,6 +294,10 @@ func (m *Manager) Add(opts ServiceOptions) error {
 	}
 
 	go m.serviceRoutine(opts.Name)
+	m.startSchedule(opts.Name, service)
+
+	m.state.setAdded(opts)
+	m.events.publish(Event{Time: time.Now(), Type: EventAdd, Service: opts.Name, NewStatus: Pending})
 
 	if m.isEligibleToRun(opts.Name) {
 		service.startSignal <- true
@@ -196,9 +318,14 @@ func (m *Manager) Delete(name string) error {
 		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
 	}
 
+	service.stopSchedule()
 	service.deleteSignal <- true
 	<-service.isDeleted
+	service.stopLogCompanion()
+	service.removeCgroup()
 	m.deleteService(name)
+	m.state.forget(name)
+	m.events.publish(Event{Time: time.Now(), Type: EventDelete, Service: name})
 
 	SminitLog.Info().Msgf("service %s is deleted", name)
 	return nil
@@ -209,17 +336,27 @@ func (m *Manager) Start(name string) error {
 	// check parents' statuses of service
 	// if all are running or successful, send start signal
 	// return
+	m.ops.Lock()
+	defer m.ops.Unlock()
+
 	service, ok := m.getService(name)
 
 	if !ok {
 		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
 	}
 
+	if service.isDisabled() {
+		return errors.Wrapf(ErrBadRequest, "service %s is disabled, it should be enabled first", name)
+	}
+
 	if service.hasStarted() {
 		return errors.Wrapf(ErrBadRequest, "service %s status is %s", name, service.Status)
 	}
 
 	service.changeStatus(Pending)
+	m.state.setStopped(name, false)
+	// a scheduled service started by the user runs now, in addition to its scheduled runs
+	service.setRunDue()
 
 	if !m.isEligibleToRun(name) {
 		return errors.Wrapf(ErrBadRequest, "service %s is still pending", name)
@@ -234,18 +371,103 @@ func (m *Manager) Start(name string) error {
 func (m *Manager) Stop(name string) error {
 	// cancel service context.
 	// return
+	m.ops.Lock()
+	defer m.ops.Unlock()
+
 	service, ok := m.getService(name)
 
 	if !ok {
 		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
 	}
 
-	service.stopSignal <- true
-	<-service.isStopped
+	if service.isDisabled() {
+		return errors.Wrapf(ErrBadRequest, "service %s is disabled", name)
+	}
+
+	service.stop()
+	m.state.setStopped(name, true)
 
 	return nil
 }
 
+// Restart stops a service that is already tracked by the manager, then starts it again.
+// If withDependents is true, services depending on it that have been started are stopped before it,
+// and are started again once it is healthy.
+func (m *Manager) Restart(name string, withDependents bool) error {
+	m.ops.Lock()
+	defer m.ops.Unlock()
+
+	service, ok := m.getService(name)
+	if !ok {
+		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	if service.isDisabled() {
+		return errors.Wrapf(ErrBadRequest, "service %s is disabled, it should be enabled first", name)
+	}
+
+	if !m.parentsAreHealthy(name) {
+		return errors.Wrapf(ErrBadRequest, "service %s can not be restarted, it is pending its parents", name)
+	}
+
+	if withDependents {
+		// dependents are stopped and left pending, they are started by startEligibleChildren once their parents are running
+		for _, dependentName := range m.startedDependents(name) {
+			dependent, ok := m.getService(dependentName)
+			if !ok {
+				continue
+			}
+			dependent.stop()
+			dependent.changeStatus(Pending)
+		}
+	}
+
+	service.stop()
+	service.changeStatus(Pending)
+	m.state.setStopped(name, false)
+	service.startSignal <- true
+
+	return nil
+}
+
+// startedDependents returns all services that directly or indirectly depend on the service with the given name, and have been started
+func (m *Manager) startedDependents(name string) []string {
+	visited := map[string]bool{name: true}
+	queue := []string{name}
+	dependents := []string{}
+
+	for len(queue) > 0 {
+		service, ok := m.getService(queue[0])
+		queue = queue[1:]
+		if !ok {
+			continue
+		}
+
+		service.mut.RLock()
+		children := make([]string, 0, len(service.children))
+		for childName := range service.children {
+			children = append(children, childName)
+		}
+		service.mut.RUnlock()
+
+		for _, childName := range children {
+			if visited[childName] {
+				continue
+			}
+			visited[childName] = true
+
+			child, ok := m.getService(childName)
+			if !ok || !child.hasStarted() {
+				continue
+			}
+			dependents = append(dependents, childName)
+			queue = append(queue, childName)
+		}
+	}
+
+	return dependents
+}
+
 // List lists all services tracked by the manager.
 func (m *Manager) List() []ServiceDesc {
 	// list all services with their statuses
@@ -255,36 +477,137 @@ func (m *Manager) List() []ServiceDesc {
 	var ret []ServiceDesc
 
 	for _, service := range services {
-		ret = append(ret, ServiceDesc{
-			Name:   service.Name,
-			Status: service.Status,
-		})
+		ret = append(ret, service.desc())
+
+		service.mut.RLock()
+		companion := service.companion
+		service.mut.RUnlock()
+		if companion != nil {
+			ret = append(ret, companion.desc(service.Name))
+		}
 	}
 	return ret
 }
 
+// Get returns the description of a service with the given name.
+func (m *Manager) Get(name string) (ServiceDesc, error) {
+	service, ok := m.getService(name)
+	if !ok {
+		return ServiceDesc{}, errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	return service.desc(), nil
+}
+
+// Logs returns the last tail log lines of a service written after since. all kept lines are returned if tail is negative.
+func (m *Manager) Logs(name string, tail int, since time.Time) ([]LogLine, error) {
+	service, ok := m.getService(name)
+	if !ok {
+		return nil, errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	return service.logs.last(tail, since), nil
+}
+
+// FollowLogs is similar to Logs, but it also returns a channel that receives every new log line of the service.
+// cancel should be called once the caller stops reading from the channel.
+func (m *Manager) FollowLogs(name string, tail int, since time.Time) (lines []LogLine, ch <-chan LogLine, cancel func(), err error) {
+	service, ok := m.getService(name)
+	if !ok {
+		return nil, nil, nil, errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	lines, ch, cancel = service.logs.follow(tail, since)
+	return lines, ch, cancel, nil
+}
+
+// Details returns the definition, state, dependencies and last logLines log lines of a service with the given name.
+func (m *Manager) Details(name string, logLines int) (ServiceDetails, error) {
+	service, ok := m.getService(name)
+	if !ok {
+		return ServiceDetails{}, errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	desc := service.desc()
+	details := ServiceDetails{
+		ServiceDesc: desc,
+		Definition:  service.options,
+		Parents:     map[string]Status{},
+		Children:    map[string]Status{},
+		Logs:        service.logs.last(logLines, time.Time{}),
+	}
+	if desc.PID != 0 {
+		details.Uptime = time.Since(desc.StartedAt)
+	}
+	if desc.Cgroup != "" {
+		if usage, err := readCgroupUsage(desc.Cgroup); err == nil {
+			details.Resources = &usage
+		}
+	}
+
+	service.mut.RLock()
+	parents := make([]string, 0, len(service.parents))
+	for parentName := range service.parents {
+		parents = append(parents, parentName)
+	}
+	children := make([]string, 0, len(service.children))
+	for childName := range service.children {
+		children = append(children, childName)
+	}
+	service.mut.RUnlock()
+
+	for _, parentName := range parents {
+		if parent, ok := m.getService(parentName); ok {
+			details.Parents[parentName] = parent.desc().Status
+		}
+	}
+	for _, childName := range children {
+		if child, ok := m.getService(childName); ok {
+			details.Children[childName] = child.desc().Status
+		}
+	}
+
+	return details, nil
+}
+
 func (m *Manager) serviceRoutine(name string) {
 	service, ok := m.getService(name)
 	if !ok {
 		return
 	}
 	ctx, cancel := context.WithCancel(context.Background())
+	// runDone is closed when the running runService routine returns, it is nil if there is no running runService routine
+	var runDone chan struct{}
 	for {
 		select {
 		case <-service.startSignal:
-			go m.runService(ctx, name)
+			if runDone != nil {
+				continue
+			}
+			runDone = make(chan struct{})
+			go func(ctx context.Context, done chan struct{}) {
+				m.runService(ctx, name)
+				close(done)
+			}(ctx, runDone)
+
+		case <-runDone:
+			runDone = nil
 
 		case <-service.stopSignal:
 			cancel()
+			if runDone != nil {
+				<-runDone
+				runDone = nil
+			}
+			service.changeStatus(Stopped)
 			ctx, cancel = context.WithCancel(context.Background())
+			service.isStopped <- true
 
 		case <-service.deleteSignal:
 			cancel()
-			if !service.hasStarted() {
-				service.isDeleted <- true
-				return
+			if runDone != nil {
+				<-runDone
 			}
-			<-service.isStopped
 			service.isDeleted <- true
 			return
 		}
@@ -297,36 +620,142 @@ func (m *Manager) runService(ctx context.Context, serviceName string) {
 		return
 	}
 
-	// service status is started
-	service.changeStatus(Started)
+	adopted := service.takeAdopted()
+	// the environment added by a triggering service is only for this run, even if the service is skipped
+	env := service.takeTriggerEnv()
+	if adopted == nil && !m.checkConditions(service) {
+		return
+	}
 
-	err := backoff.Retry(func() error {
+	if adopted == nil {
+		// service status is started
+		service.changeStatus(Started)
+
+		if delay := service.options.StartDelay; delay > 0 {
+			service.logger().Info().Msgf("delaying start of service %s by %s", service.Name, delay)
+			timer := time.NewTimer(delay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+		}
+	}
+
+	if service.takeRunDue() && service.options.Schedule.IsSet() {
+		now := time.Now()
+		service.setLastRun(now)
+		m.state.setLastRun(service.Name, now)
+	}
+
+	// the output is prepared in the retry loop, so a sink that is not available yet, like a syslog socket, does not prevent restarts
+	var output *serviceOutput
+	defer func() {
+		if output != nil {
+			output.close()
+		}
+	}()
+
+	cgroup, err := m.prepareCgroup(service)
+	if err != nil {
+		service.logger().Warn().Msgf("resource limits of service %s are not applied. %s", service.Name, err.Error())
+	}
+	// the process and the hooks of the run are started with the same environment and options
+	run := runEnv{env: env, opts: service.execOptions(cgroup)}
+
+	attempts := 0
+	err = backoff.Retry(func() error {
 		select {
 		case <-ctx.Done():
-			service.changeStatus(Stopped)
-			service.isStopped <- true
 			return backoff.Permanent(fmt.Errorf("service %s was stopped", service.Name))
 
 		default:
-			splittedCmd := strings.Split(service.cmdStr, " ")
-			cmd := exec.CommandContext(ctx, splittedCmd[0], splittedCmd[1:]...)
-			if service.log == "stdout" {
-				cmd.Stdout = &service.stdout
-				cmd.Stderr = &service.stderr
+			attempts++
+			if attempts > 1 {
+				service.incrementRestarts()
 			}
 
-			err := cmd.Start()
-			if err != nil {
-				SminitLog.Error().Msgf("error while starting process %s. %s", service.Name, err.Error())
-				return errors.New("restarting service")
+			if output == nil {
+				var err error
+				output, err = service.newServiceOutput()
+				if err != nil {
+					m.failService(service, ReasonStartError)
+					service.logger().Error().Msgf("error while preparing output of service %s. %s", service.Name, err.Error())
+					return errors.New("restarting service")
+				}
 			}
 
-			if !isHealthy(ctx, service) {
-				err = cmd.Process.Kill()
+			var process *serviceProcess
+			// an adopted process that was running is not checked again, it was found healthy before sminit re-executed
+			healthy := false
+			if adopted != nil {
+				process = adoptProcess(adopted.PID, adopted.Stdout, adopted.Stderr, output)
+				process.cgroup = cgroup
+				process.stopTimeout = service.stopTimeout()
+				service.setProcess(process)
+				service.restoreStartTime(adopted.StartedAt)
+				healthy = adopted.Status == Running
+				adopted = nil
+			} else {
+				if err := service.runHooks(ctx, hookStartPre, service.options.ExecStartPre, 0, run); err != nil {
+					if ctx.Err() != nil {
+						return backoff.Permanent(fmt.Errorf("service %s was stopped", service.Name))
+					}
+					m.failService(service, ReasonStartPreFailed)
+					service.logger().Error().Msg(err.Error())
+					return errors.New("restarting service")
+				}
+
+				// the process is started and tracked holding the handover lock, so it is not started while services are handed over
+				var err error
+				handoverLock.RLock()
+				process, err = startProcess(service.cmdStr, output, run)
+				if err == nil {
+					process.cgroup = cgroup
+					process.stopTimeout = service.stopTimeout()
+					service.setProcess(process)
+				}
+				handoverLock.RUnlock()
 				if err != nil {
-					SminitLog.Error().Msgf("error killing process %s. %s", service.Name, err.Error())
+					m.failService(service, ReasonStartError)
+					service.logger().Error().Msgf("error while starting process %s. %s", service.Name, err.Error())
+					return errors.New("restarting service")
+				}
+			}
+
+			if !healthy {
+				healthCtx, cancelHealth := ctx, context.CancelFunc(func() {})
+				if timeout := service.options.StartTimeout; timeout > 0 {
+					healthCtx, cancelHealth = context.WithTimeout(ctx, timeout)
+				}
+				healthy = isHealthy(healthCtx, service)
+				timedOut := errors.Is(healthCtx.Err(), context.DeadlineExceeded)
+				cancelHealth()
+
+				if !healthy {
+					err := process.kill()
+					if err != nil {
+						service.logger().Error().Msgf("error killing process %s. %s", service.Name, err.Error())
+					}
+					status, _ := process.wait(ctx)
+					output.flush()
+					service.recordExit(status)
+					service.runStopHooks(hookStopPost, service.options.ExecStopPost, 0, run)
+					if ctx.Err() == nil {
+						reason := ReasonHealthTimeout
+						if timedOut {
+							reason = ReasonStartTimeout
+							service.logger().Error().Msgf("service %s did not become healthy within %s", service.Name, service.options.StartTimeout)
+						}
+						m.failService(service, reason)
+					}
+					return errors.New("service is not healthy. restarting...")
+				}
+
+				if err := service.runHooks(ctx, hookStartPost, service.options.ExecStartPost, process.pid, run); err != nil {
+					service.logger().Error().Msg(err.Error())
 				}
-				return errors.New("service is not healthy. restarting...")
 			}
 
 			// service status is running
@@ -334,15 +763,23 @@ func (m *Manager) runService(ctx context.Context, serviceName string) {
 
 			m.startEligibleChildren(service.Name)
 
-			err = cmd.Wait()
+			status, err := service.waitRunning(ctx, process, run)
+			output.flush()
+			service.recordExit(status)
+			service.runStopHooks(hookStopPost, service.options.ExecStopPost, 0, run)
+			if err == nil {
+				err = exitError(status)
+			}
 			if err != nil {
-				service.changeStatus(Failed)
-				SminitLog.Error().Msgf("error while running process %s. %s", service.Name, err.Error())
+				if ctx.Err() == nil {
+					m.failService(service, ReasonNonZeroExit)
+				}
+				service.logger().Error().Msgf("error while running process %s. %s", service.Name, err.Error())
 
 				return errors.New("restarting service")
 			}
 
-			service.changeStatus(Successful)
+			m.succeedService(service)
 			if service.oneShot {
 				return backoff.Permanent(fmt.Errorf("service %s has finished", service.Name))
 			}
@@ -350,9 +787,9 @@ func (m *Manager) runService(ctx context.Context, serviceName string) {
 
 			return errors.New("restarting service")
 		}
-	}, newExponentialBackOff())
+	}, backoff.WithContext(newExponentialBackOff(), ctx))
 
-	SminitLog.Info().Msg(err.Error())
+	service.logger().Info().Msg(err.Error())
 
 	if service.oneShot {
 		return
@@ -360,11 +797,16 @@ func (m *Manager) runService(ctx context.Context, serviceName string) {
 
 }
 
-// this function will return false only if context was cancelled or backoff timesout, and true if cmd.Run() returned nil, i.e process is healthy
+// this function will return false only if context was cancelled or backoff timesout, and true if cmd.Run() returned nil, i.e process is healthy.
+// if ctx has a deadline, the health check is retried until the deadline instead of for a minute.
 func isHealthy(ctx context.Context, service *Service) bool {
 	exponentialBackoff := newExponentialBackOff()
 	exponentialBackoff.MaxElapsedTime = time.Minute
+	if _, ok := ctx.Deadline(); ok {
+		exponentialBackoff.MaxElapsedTime = 0
+	}
 	healthy := false
+	start := time.Now()
 	err := backoff.Retry(func() error {
 		select {
 		case <-ctx.Done():
@@ -377,10 +819,15 @@ func isHealthy(ctx context.Context, service *Service) bool {
 				healthy = true
 				return backoff.Permanent(errors.New("health check is successful"))
 			}
+			service.recordHealthCheckFailure()
 			return errors.New("health check failed")
 		}
 	}, exponentialBackoff)
-	SminitLog.Trace().Msgf("service %s health check: %s", service.Name, err.Error())
+	// a health check that timed out is recorded as failed, one that was cancelled because the service was stopped is not recorded
+	if !errors.Is(ctx.Err(), context.Canceled) {
+		service.recordHealthCheck(time.Since(start), healthy)
+	}
+	service.logger().Trace().Msgf("service %s health check: %s", service.Name, err.Error())
 	return healthy
 
 }
@@ -406,6 +853,12 @@ func (m *Manager) startEligibleChildren(name string) {
 	}
 }
 
+// stop stops the process of the service if it is running, and waits until the service is stopped
+func (s *Service) stop() {
+	s.stopSignal <- true
+	<-s.isStopped
+}
+
 func (s *Service) hasStarted() bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
@@ -413,16 +866,32 @@ func (s *Service) hasStarted() bool {
 	return s.Status == Running || s.Status == Started || s.Status == Successful || s.Status == Failed
 }
 
+func (s *Service) isDisabled() bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.Status == Disabled
+}
+
 func (m *Manager) isEligibleToRun(name string) bool {
 	// a service is said to be eligible to run if it is pending, and all its parents are running or successful (healthy)
 	service, _ := m.getService(name)
 
 	service.mut.RLock()
-	defer service.mut.RUnlock()
+	pending := service.Status == Pending
+	// scheduled services are only started when a run is due
+	due := !service.options.Schedule.IsSet() || service.runDue
+	service.mut.RUnlock()
 
-	if service.Status != Pending {
-		return false
-	}
+	return pending && due && m.parentsAreHealthy(name)
+}
+
+// parentsAreHealthy returns true if all parents of the service are running, successful or skipped
+func (m *Manager) parentsAreHealthy(name string) bool {
+	service, _ := m.getService(name)
+
+	service.mut.RLock()
+	defer service.mut.RUnlock()
 
 	for parentName := range service.parents {
 		parent, ok := m.getService(parentName)
@@ -434,41 +903,214 @@ func (m *Manager) isEligibleToRun(name string) bool {
 	return true
 }
 
+// isRunningOrSuccessful returns true if the service is running or successful. skipped services count as successful, so their dependents are started.
 func (s *Service) isRunningOrSuccessful() bool {
 	s.mut.RLock()
 	defer s.mut.RUnlock()
-	return s.Status == Running || s.Status == Successful
+	return s.Status == Running || s.Status == Successful || s.Status == Skipped
+
+}
+
+// changeStatus sets the status of the service, and returns true if it has changed
+func (s *Service) changeStatus(newStatus Status) bool {
+	s.mut.Lock()
+	event := s.setStatusLocked(newStatus)
+	s.mut.Unlock()
 
+	if event != nil {
+		s.events.publish(*event)
+	}
+	return event != nil
 }
 
-func (s *Service) changeStatus(newStatus Status) {
+// fail marks the service as failed with the given reason, and returns true if it was not failed already
+func (s *Service) fail(reason Reason) bool {
 	s.mut.Lock()
+	s.failureReason = reason
+	event := s.setStatusLocked(Failed)
+	s.mut.Unlock()
+
+	if event != nil {
+		event.Reason = reason
+		s.events.publish(*event)
+	}
+	return event != nil
+}
+
+// setStatusLocked changes service status, and returns the status event to publish if the status has changed.
+// s.mut should be held by the caller.
+func (s *Service) setStatusLocked(newStatus Status) *Event {
+	oldStatus := s.Status
 	s.Status = newStatus
+	s.lastChange = time.Now()
+
+	if oldStatus == newStatus {
+		return nil
+	}
+	return &Event{
+		Time:      s.lastChange,
+		Type:      EventStatus,
+		Service:   s.Name,
+		OldStatus: oldStatus,
+		NewStatus: newStatus,
+		PID:       s.pid,
+	}
+}
+
+func (s *Service) setProcess(process *serviceProcess) {
+	s.mut.Lock()
+	s.process = process
+	s.pid = process.pid
+	s.startedAt = time.Now()
 	s.mut.Unlock()
 }
 
-func newService(service ServiceOptions) *Service {
-	stdout := stdoutLogger{
-		serviceName: service.Name,
+// recordExit records the exit code or signal of a terminated process, and clears its pid
+func (s *Service) recordExit(status syscall.WaitStatus) {
+	exit := exitInfo(status)
+
+	s.mut.Lock()
+	pid := s.pid
+	s.pid = 0
+	s.process = nil
+	s.lastExit = exit
+	s.mut.Unlock()
+
+	s.events.publish(Event{Time: exit.Time, Type: EventExit, Service: s.Name, PID: pid, Exit: exit})
+}
+
+// exitInfo describes how a terminated process has exited
+func exitInfo(status syscall.WaitStatus) *ExitInfo {
+	exit := ExitInfo{
+		Code: status.ExitStatus(),
+		Time: time.Now(),
+	}
+	if status.Signaled() {
+		exit.Signal = unix.SignalName(status.Signal())
+	}
+	return &exit
+}
+
+// stopTimeout returns how long the processes of the service have to terminate after SIGTERM before they are killed
+func (s *Service) stopTimeout() time.Duration {
+	if s.options.StopTimeout > 0 {
+		return s.options.StopTimeout
+	}
+	return defaultStopTimeout
+}
+
+func (s *Service) incrementRestarts() {
+	s.mut.Lock()
+	s.restarts++
+	s.mut.Unlock()
+}
+
+func (s *Service) recordHealthCheckFailure() {
+	s.mut.Lock()
+	s.healthCheckFailures++
+	s.mut.Unlock()
+}
+
+// recordHealthCheck records the duration of a health check, and publishes its result
+func (s *Service) recordHealthCheck(d time.Duration, healthy bool) {
+	s.mut.Lock()
+	s.healthCheckDuration = d
+	pid := s.pid
+	s.mut.Unlock()
+
+	s.events.publish(Event{Time: time.Now(), Type: EventHealth, Service: s.Name, PID: pid, Healthy: healthy})
+}
+
+// logger returns sminit logger with the service name attached to every event, and the log level of the service if it overrides sminit log level
+func (s *Service) logger() *zerolog.Logger {
+	base := SminitLog
+	if logConfig.syslog {
+		base = s.syslogLogger()
+	}
+
+	logger := base.With().Str("service", s.Name).Logger()
+	if s.options.LogLevel != "" {
+		if level, err := zerolog.ParseLevel(s.options.LogLevel); err == nil {
+			logger = logger.Level(level)
+		}
+	}
+	return &logger
+}
+
+// syslogLogger returns a logger that copies the logs of the service to syslog tagged with the service name.
+// SminitLog is returned if connecting to syslog fails.
+func (s *Service) syslogLogger() zerolog.Logger {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	if s.daemonLogger == nil {
+		logger, err := newLogger(s.Name)
+		if err != nil {
+			SminitLog.Error().Msgf("failed to create syslog logger for service %s. %s", s.Name, err.Error())
+			return SminitLog
+		}
+		s.daemonLogger = &logger
+	}
+	return *s.daemonLogger
+}
+
+func (s *Service) desc() ServiceDesc {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	desc := ServiceDesc{
+		Name:          s.Name,
+		Status:        s.Status,
+		PID:           s.pid,
+		StartedAt:     s.startedAt,
+		FailureReason: s.failureReason,
+		Restarts:      s.restarts,
+		LastChange:    s.lastChange,
+
+		HealthCheckDuration: s.healthCheckDuration,
+		HealthCheckFailures: s.healthCheckFailures,
+		NextRun:             s.nextRun,
+		Cgroup:              s.cgroup,
+		LastRun:             s.lastRun,
+	}
+	if s.lastExit != nil {
+		exit := *s.lastExit
+		desc.LastExit = &exit
 	}
-	stderr := stderrLogger{
-		serviceName: service.Name,
+	if s.lastReload != nil {
+		reload := *s.lastReload
+		desc.LastReload = &reload
 	}
+	if s.Status == Skipped {
+		desc.SkipReason = s.skipReason
+	}
+	return desc
+}
+
+func newService(service ServiceOptions) *Service {
+	logs := newLogBuffer(maxLogLines)
 
 	healthCheck := service.HealthCheck
 	if healthCheck == "" {
 		healthCheck = "sleep 1"
 	}
+	service.HealthCheck = healthCheck
+
+	status := Pending
+	if service.Disabled {
+		status = Disabled
+	}
 
 	newService := Service{
 		Name:         service.Name,
-		Status:       Pending,
+		Status:       status,
+		lastChange:   time.Now(),
 		log:          service.Log,
 		healthCheck:  healthCheck,
 		cmdStr:       service.Cmd,
 		oneShot:      service.OneShot,
-		stdout:       stdout,
-		stderr:       stderr,
+		logs:         logs,
+		options:      service,
 		children:     map[string]bool{},
 		parents:      map[string]bool{},
 		startSignal:  make(chan bool),
@@ -505,16 +1147,6 @@ func newExponentialBackOff() *backoff.ExponentialBackOff {
 	return &b
 }
 
-func (l *stderrLogger) Write(p []byte) (int, error) {
-	SminitLog.Error().Str("component", fmt.Sprintf("%s:", l.serviceName)).Msg(string(p[:len(p)-1]))
-	return len(p), nil
-}
-
-func (l *stdoutLogger) Write(p []byte) (int, error) {
-	SminitLog.Info().Str("component", fmt.Sprintf("%s:", l.serviceName)).Msg(string(p[:len(p)-1]))
-	return len(p), nil
-}
-
 /*
 	manager is responsible for manipulating services
 	one instance of the manager should be acquired by the server
@@ -1,12 +1,25 @@
 package manager
 
 import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"syscall"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
 )
 
+func TestMain(m *testing.M) {
+	// processes of services are started by the test binary acting as the exec helper
+	RunExecHelper()
+	os.Exit(m.Run())
+}
+
 func TestManager(t *testing.T) {
 	t.Run("simple_test", func(t *testing.T) {
 		loadedServices := map[string]ServiceOptions{
@@ -157,4 +170,1217 @@ func TestManager(t *testing.T) {
 		assert.True(t, list[0].Status != Stopped)
 	})
 
+	t.Run("status_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "false",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(time.Second)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonNonZeroExit, s1.FailureReason)
+		assert.NotNil(t, s1.LastExit)
+		assert.Equal(t, 1, s1.LastExit.Code)
+		assert.True(t, s1.Restarts > 0, "restarts is %d", s1.Restarts)
+
+		_, err = manager.Get("s2")
+		assert.Error(t, err)
+	})
+
+	t.Run("details_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "echo hello",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "echo world",
+				Log:         "stdout",
+				After:       []string{"s1"},
+				OneShot:     true,
+				HealthCheck: "",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Details("s1", 10)
+		assert.NoError(t, err)
+		assert.Len(t, s1.Logs, 1)
+		assert.Equal(t, "hello", s1.Logs[0].Line)
+		assert.Equal(t, StreamStdout, s1.Logs[0].Stream)
+		assert.Contains(t, s1.Children, "s2")
+
+		s2, err := manager.Details("s2", 10)
+		assert.NoError(t, err)
+		assert.Equal(t, "sleep 1", s2.Definition.HealthCheck)
+		assert.Equal(t, map[string]Status{"s1": Successful}, s2.Parents)
+	})
+
+	t.Run("restart_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{"s1"},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s2.Status)
+
+		err = manager.Restart("s1", true)
+		assert.NoError(t, err)
+
+		time.Sleep(500 * time.Millisecond)
+
+		restartedS1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, restartedS1.Status)
+		assert.NotEqual(t, s1.PID, restartedS1.PID)
+
+		restartedS2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, restartedS2.Status)
+		assert.NotEqual(t, s2.PID, restartedS2.PID)
+
+		assert.NoError(t, manager.Stop("s2"))
+		assert.NoError(t, manager.Stop("s1"))
+	})
+
+	t.Run("signal_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		sig, err := ParseSignal("usr1")
+		assert.NoError(t, err)
+		assert.Equal(t, syscall.SIGUSR1, sig)
+
+		_, err = ParseSignal("SIGNOPE")
+		assert.Error(t, err)
+
+		err = manager.Signal("s1", syscall.SIGTERM, false)
+		assert.NoError(t, err)
+
+		time.Sleep(200 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.NotNil(t, s1.LastExit)
+		assert.Equal(t, "SIGTERM", s1.LastExit.Signal)
+
+		assert.NoError(t, manager.Stop("s1"))
+	})
+
+	t.Run("reload_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				Reload:      "false",
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				Reload:      "SIGCONT",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		err = manager.Reload("s1")
+		assert.ErrorIs(t, err, ErrReloadFailed)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+		assert.NotNil(t, s1.LastReload)
+		assert.NotEmpty(t, s1.LastReload.Error)
+
+		err = manager.Reload("s2")
+		assert.NoError(t, err)
+
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s2.Status)
+		assert.Empty(t, s2.LastReload.Error)
+
+		assert.NoError(t, manager.Stop("s1"))
+		assert.NoError(t, manager.Stop("s2"))
+	})
+
+	t.Run("log_pipe_test", func(t *testing.T) {
+		logPath := path.Join(t.TempDir(), "s1.log")
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "echo hello",
+				Log:         LogPipe,
+				LogCmd:      "tee " + logPath,
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		list := manager.List()
+		assert.Len(t, list, 2)
+		for _, desc := range list {
+			if desc.Name == "s1/log" {
+				assert.Equal(t, "s1", desc.LinkedTo)
+				assert.Equal(t, Running, desc.Status)
+			}
+		}
+
+		err = manager.Delete("s1")
+		assert.NoError(t, err)
+
+		content, err := os.ReadFile(logPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello\n", string(content))
+	})
+
+	t.Run("events_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		_, ch, cancel := manager.FollowEvents("s2")
+		defer cancel()
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		events := manager.Events("s1")
+		assert.Len(t, events, 3)
+		assert.Equal(t, EventStatus, events[0].Type)
+		assert.Equal(t, Pending, events[0].OldStatus)
+		assert.Equal(t, Started, events[0].NewStatus)
+		assert.Equal(t, EventHealth, events[1].Type)
+		assert.True(t, events[1].Healthy)
+		assert.NotZero(t, events[1].PID)
+		assert.Equal(t, Running, events[2].NewStatus)
+
+		err = manager.Add(ServiceOptions{Name: "s2", Cmd: "false", After: []string{}, HealthCheck: "true"})
+		assert.NoError(t, err)
+
+		event := <-ch
+		assert.Equal(t, EventAdd, event.Type)
+		assert.Equal(t, "s2", event.Service)
+		event = <-ch
+		assert.Equal(t, Started, event.NewStatus)
+
+		assert.NoError(t, manager.Delete("s2"))
+		assert.NoError(t, manager.Stop("s1"))
+
+		events = manager.Events("s2")
+		assert.Equal(t, EventDelete, events[len(events)-1].Type)
+		for _, event := range events {
+			if event.NewStatus == Failed {
+				assert.Equal(t, ReasonNonZeroExit, event.Reason)
+			}
+		}
+		assert.Equal(t, Stopped, manager.Events("s1")[len(manager.Events("s1"))-1].NewStatus)
+	})
+
+	t.Run("state_test", func(t *testing.T) {
+		stateDir := t.TempDir()
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		state, err := loadState(stateDir)
+		assert.NoError(t, err)
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+		manager.setState(state)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		assert.NoError(t, manager.Stop("s1"))
+		assert.NoError(t, manager.Add(ServiceOptions{Name: "s2", Cmd: "sleep 10", After: []string{}, HealthCheck: "true"}))
+		time.Sleep(500 * time.Millisecond)
+		assert.NoError(t, manager.Stop("s2"))
+
+		// sminit restarts with the same definitions
+		loadedServices = map[string]ServiceOptions{"s1": loadedServices["s1"]}
+		state, err = loadState(stateDir)
+		assert.NoError(t, err)
+		state.restoreServices(loadedServices)
+		assert.Contains(t, loadedServices, "s2")
+
+		manager, err = NewManager(loadedServices)
+		assert.NoError(t, err)
+		manager.setState(state)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Stopped, s1.Status)
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, Stopped, s2.Status)
+
+		assert.NoError(t, manager.Start("s1"))
+		assert.NoError(t, manager.Delete("s2"))
+
+		state, err = loadState(stateDir)
+		assert.NoError(t, err)
+		assert.Empty(t, state.state.Stopped)
+		assert.Empty(t, state.state.Added)
+
+		assert.NoError(t, manager.Stop("s1"))
+	})
+
+	t.Run("enable_disable_test", func(t *testing.T) {
+		stateDir := t.TempDir()
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				Disabled:    true,
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		state, err := loadState(stateDir)
+		assert.NoError(t, err)
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+		manager.setState(state)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Disabled, s1.Status)
+		assert.Error(t, manager.Start("s1"))
+
+		assert.NoError(t, manager.Enable("s1"))
+		assert.NoError(t, manager.Disable("s2"))
+		assert.Error(t, manager.Disable("s2"))
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err = manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, Disabled, s2.Status)
+		assert.Zero(t, s2.PID)
+
+		// sminit restarts with the same definitions
+		state, err = loadState(stateDir)
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]bool{"s1": false, "s2": true}, state.state.Disabled)
+
+		assert.NoError(t, manager.Stop("s1"))
+		assert.NoError(t, manager.Enable("s2"))
+		assert.NoError(t, manager.Stop("s2"))
+	})
+
+	t.Run("adopt_test", func(t *testing.T) {
+		// a process started by another sminit instance, with its stdout pipe handed over
+		r, w, err := os.Pipe()
+		assert.NoError(t, err)
+		cmd := exec.Command("sh", "-c", "echo adopted; exec sleep 10")
+		cmd.Stdout = w
+		assert.NoError(t, cmd.Start())
+		w.Close()
+		pid := cmd.Process.Pid
+		_ = cmd.Process.Release()
+		stdoutFd, err := syscall.Dup(int(r.Fd()))
+		assert.NoError(t, err)
+		r.Close()
+
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{"s1"},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		startedAt := time.Now().Add(-time.Minute)
+		manager.adoptServices([]adoptedService{
+			{ServiceDesc: ServiceDesc{Name: "s1", Status: Running, PID: pid, StartedAt: startedAt, Restarts: 2}, Stdout: stdoutFd, Stderr: -1},
+			{ServiceDesc: ServiceDesc{Name: "s2", Status: Failed, Restarts: 1}, Stdout: -1, Stderr: -1},
+		})
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+		assert.Equal(t, pid, s1.PID)
+		assert.Equal(t, 2, s1.Restarts)
+		assert.True(t, s1.StartedAt.Equal(startedAt))
+
+		logs, err := manager.Logs("s1", -1, time.Time{})
+		assert.NoError(t, err)
+		assert.Len(t, logs, 1)
+		assert.Equal(t, "adopted", logs[0].Line)
+
+		// a service that was restarting when it was handed over is started again
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s2.Status)
+		assert.NotZero(t, s2.PID)
+
+		assert.NoError(t, manager.Stop("s1"))
+		assert.NoError(t, manager.Stop("s2"))
+
+		s1, err = manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Stopped, s1.Status)
+		assert.Equal(t, "SIGTERM", s1.LastExit.Signal)
+	})
+	t.Run("schedule_test", func(t *testing.T) {
+		stateDir := t.TempDir()
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "true",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+				Schedule:    ScheduleOptions{Every: 600 * time.Millisecond},
+			},
+		}
+		state, err := loadState(stateDir)
+		assert.NoError(t, err)
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+		manager.setState(state)
+
+		manager.fireServices()
+
+		time.Sleep(200 * time.Millisecond)
+
+		// scheduled services are not started until their first run
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Pending, s1.Status)
+		assert.True(t, s1.LastRun.IsZero())
+		assert.True(t, s1.NextRun.After(time.Now()))
+
+		time.Sleep(700 * time.Millisecond)
+
+		s1, err = manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Successful, s1.Status)
+		assert.False(t, s1.LastRun.IsZero())
+		assert.True(t, s1.NextRun.After(s1.LastRun))
+
+		state, err = loadState(stateDir)
+		assert.NoError(t, err)
+		assert.Contains(t, state.state.LastRuns, "s1")
+
+		// runs are skipped while the service is stopped
+		assert.NoError(t, manager.Stop("s1"))
+		time.Sleep(500 * time.Millisecond)
+		s1, err = manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Stopped, s1.Status)
+
+		assert.NoError(t, manager.Delete("s1"))
+	})
+	t.Run("start_delay_timeout_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				StartDelay:  700 * time.Millisecond,
+			},
+			"s2": {
+				Name:         "s2",
+				Cmd:          "sleep 10",
+				Log:          "stdout",
+				After:        []string{},
+				OneShot:      false,
+				HealthCheck:  "false",
+				StartTimeout: 300 * time.Millisecond,
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(300 * time.Millisecond)
+
+		// the process is not started until the delay has passed
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Started, s1.Status)
+		assert.Zero(t, s1.PID)
+
+		// waitFor polls the service with the given name until done returns true or the deadline has passed
+		waitFor := func(name string, done func(ServiceDesc) bool) ServiceDesc {
+			deadline := time.Now().Add(5 * time.Second)
+			for {
+				desc, err := manager.Get(name)
+				assert.NoError(t, err)
+				if done(desc) || time.Now().After(deadline) {
+					return desc
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		}
+
+		s1 = waitFor("s1", func(desc ServiceDesc) bool { return desc.Status == Running })
+		assert.Equal(t, Running, s1.Status)
+		assert.NotZero(t, s1.PID)
+
+		s2 := waitFor("s2", func(desc ServiceDesc) bool { return desc.Status == Failed })
+		assert.Equal(t, Failed, s2.Status)
+		assert.Equal(t, ReasonStartTimeout, s2.FailureReason)
+		assert.NotZero(t, s2.HealthCheckFailures)
+
+		// s2 is restarted after a backoff interval following its timed out health check
+		s2 = waitFor("s2", func(desc ServiceDesc) bool { return desc.Restarts > 0 })
+		assert.True(t, s2.Restarts > 0, "restarts is %d", s2.Restarts)
+		assert.Equal(t, ReasonStartTimeout, s2.FailureReason)
+
+		assert.NoError(t, manager.Stop("s1"))
+		assert.NoError(t, manager.Stop("s2"))
+	})
+	t.Run("hooks_test", func(t *testing.T) {
+		dir := t.TempDir()
+		hookFile := func(name string) string {
+			return path.Join(dir, name)
+		}
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:          "s1",
+				Cmd:           "sleep 10",
+				Log:           "stdout",
+				After:         []string{},
+				OneShot:       false,
+				HealthCheck:   "true",
+				ExecStartPre:  []string{"mkdir " + hookFile("pre")},
+				ExecStartPost: []string{"touch " + hookFile("pre/post")},
+				ExecStop:      []string{"touch " + hookFile("pre/stop")},
+				ExecStopPost:  []string{"mv " + hookFile("pre/stop") + " " + hookFile("stopped")},
+			},
+			"s2": {
+				Name:         "s2",
+				Cmd:          "sleep 10",
+				Log:          "stdout",
+				After:        []string{},
+				OneShot:      false,
+				HealthCheck:  "true",
+				ExecStartPre: []string{"sleep 10"},
+				HookTimeout:  200 * time.Millisecond,
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+		assert.FileExists(t, hookFile("pre/post"))
+
+		// a failed pre-start hook aborts the start
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonStartPreFailed, s2.FailureReason)
+		assert.Zero(t, s2.PID)
+
+		// stop hooks run before the process is killed, stop-post hooks once it has terminated
+		assert.NoError(t, manager.Stop("s1"))
+		assert.FileExists(t, hookFile("stopped"))
+
+		s1, err = manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Stopped, s1.Status)
+
+		assert.NoError(t, manager.Stop("s2"))
+	})
+	t.Run("trigger_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "false",
+				Log:         "stdout",
+				After:       []string{"s2"},
+				OneShot:     false,
+				HealthCheck: "true",
+				OnFailure:   []string{"s2"},
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "env",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+			},
+			"s3": {
+				Name:        "s3",
+				Cmd:         "sleep 0.3",
+				Log:         "stdout",
+				After:       []string{"s4"},
+				OneShot:     true,
+				HealthCheck: "true",
+				OnSuccess:   []string{"s4"},
+			},
+			"s4": {
+				Name:        "s4",
+				Cmd:         "env",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(time.Second)
+
+		// triggerEnv returns the lines printed by env that were added by the triggering service
+		triggerEnv := func(name string) []string {
+			logs, err := manager.Logs(name, -1, time.Time{})
+			assert.NoError(t, err)
+			lines := []string{}
+			for _, log := range logs {
+				if strings.HasPrefix(log.Line, "SMINIT_TRIGGER") {
+					lines = append(lines, log.Line)
+				}
+			}
+			return lines
+		}
+
+		s2Env := triggerEnv("s2")
+		assert.GreaterOrEqual(t, len(s2Env), 4)
+		assert.Equal(t, []string{
+			"SMINIT_TRIGGER=failure",
+			"SMINIT_TRIGGER_SERVICE=s1",
+			"SMINIT_TRIGGER_REASON=non-zero-exit",
+			"SMINIT_TRIGGER_EXIT_CODE=1",
+		}, s2Env[:4])
+
+		// the first run of s4 was not triggered
+		assert.Equal(t, []string{
+			"SMINIT_TRIGGER=success",
+			"SMINIT_TRIGGER_SERVICE=s3",
+			"SMINIT_TRIGGER_EXIT_CODE=0",
+		}, triggerEnv("s4"))
+
+		assert.NoError(t, manager.Stop("s1"))
+	})
+	t.Run("conditions_test", func(t *testing.T) {
+		flag := path.Join(t.TempDir(), "flag")
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				Conditions:  ConditionOptions{PathExists: []string{flag}},
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{"s1"},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Skipped, s1.Status)
+		assert.Contains(t, s1.SkipReason, flag)
+		assert.Zero(t, s1.PID)
+
+		// skipped services do not prevent their dependents from starting
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s2.Status)
+
+		// conditions are checked again when the service is started
+		assert.NoError(t, os.WriteFile(flag, nil, 0644))
+		assert.NoError(t, manager.Start("s1"))
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err = manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+		assert.Empty(t, s1.SkipReason)
+
+		assert.NoError(t, manager.Stop("s1"))
+		assert.NoError(t, manager.Stop("s2"))
+	})
+	t.Run("cgroup_test", func(t *testing.T) {
+		// a fake cgroup v2 hierarchy, interface files are regular files
+		parent := t.TempDir()
+		assert.NoError(t, os.WriteFile(path.Join(parent, "cgroup.controllers"), []byte("cpu io memory pids"), 0644))
+		root := path.Join(parent, "sminit")
+
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				Resources:   ResourceOptions{MemoryMax: 64 << 20, PidsMax: 16},
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+		manager.setCgroupRoot(root)
+
+		// the process of s2 could not be placed in its cgroup
+		assert.NoError(t, os.MkdirAll(path.Join(root, "s2", "cgroup.procs"), 0755))
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		cgroup := path.Join(root, "s1")
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+		assert.Equal(t, cgroup, s1.Cgroup)
+
+		readFile := func(name string) string {
+			content, err := os.ReadFile(path.Join(cgroup, name))
+			assert.NoError(t, err)
+			return string(content)
+		}
+		assert.Equal(t, "67108864", readFile("memory.max"))
+		assert.Equal(t, "16", readFile("pids.max"))
+		assert.Equal(t, fmt.Sprint(s1.PID), readFile("cgroup.procs"))
+
+		// the whole cgroup is killed when the service is stopped
+		assert.NoError(t, manager.Stop("s1"))
+		assert.Equal(t, "1", readFile("cgroup.kill"))
+
+		assert.NoError(t, manager.Delete("s1"))
+
+		// a process that could not be placed in its cgroup is not started
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonStartError, s2.FailureReason)
+		assert.Zero(t, s2.PID)
+		assert.Nil(t, s2.LastExit)
+
+		assert.NoError(t, manager.Stop("s2"))
+	})
+	t.Run("tunables_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				Rlimits:     RlimitOptions{Nofile: "1024:4096", Core: "0"},
+				Nice:        5,
+				OOMScoreAdj: 100,
+				CPUAffinity: []int{0},
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "sleep 10",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				// there is no such cpu
+				CPUAffinity: []int{1000},
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+
+		nofile := unix.Rlimit{}
+		assert.NoError(t, unix.Prlimit(s1.PID, unix.RLIMIT_NOFILE, nil, &nofile))
+		assert.Equal(t, unix.Rlimit{Cur: 1024, Max: 4096}, nofile)
+
+		// getpriority returns 20 - nice
+		prio, err := unix.Getpriority(unix.PRIO_PROCESS, s1.PID)
+		assert.NoError(t, err)
+		assert.Equal(t, 15, prio)
+
+		oomScoreAdj, err := os.ReadFile(fmt.Sprintf("/proc/%d/oom_score_adj", s1.PID))
+		assert.NoError(t, err)
+		assert.Equal(t, "100", strings.TrimSpace(string(oomScoreAdj)))
+
+		set := unix.CPUSet{}
+		assert.NoError(t, unix.SchedGetaffinity(s1.PID, &set))
+		assert.Equal(t, 1, set.Count())
+		assert.True(t, set.IsSet(0))
+
+		// a process whose options could not be applied does not execute its command, and the service is failed
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		assert.Equal(t, ReasonStartError, s2.FailureReason)
+		assert.Zero(t, s2.PID)
+		assert.Nil(t, s2.LastExit)
+
+		assert.NoError(t, manager.Stop("s1"))
+		assert.NoError(t, manager.Stop("s2"))
+	})
+
+	t.Run("log_buffer_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "echo hello",
+				Log:         "null",
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         "echo hello",
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		// recent lines are kept even if the output of the service is discarded
+		for _, name := range []string{"s1", "s2"} {
+			logs, err := manager.Logs(name, -1, time.Time{})
+			assert.NoError(t, err)
+			if assert.Len(t, logs, 1, name) {
+				assert.Equal(t, "hello", logs[0].Line)
+			}
+		}
+	})
+
+	t.Run("log_pipe_blocked_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "seq 1 100000",
+				Log:         LogPipe,
+				LogCmd:      "sleep 30",
+				After:       []string{},
+				OneShot:     true,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(time.Second)
+
+		// a companion that does not read its input makes lines be dropped instead of blocking the service
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Successful, s1.Status)
+
+		start := time.Now()
+		assert.NoError(t, manager.Delete("s1"))
+		assert.Less(t, time.Since(start), 2*companionStopTimeout)
+	})
+
+	t.Run("restore_invalid_test", func(t *testing.T) {
+		stateDir := t.TempDir()
+		state, err := loadState(stateDir)
+		assert.NoError(t, err)
+		state.setAdded(ServiceOptions{Name: "s2", Cmd: "sleep 10", After: []string{"s1"}})
+		state.setAdded(ServiceOptions{Name: "s3", Cmd: "sleep 10", After: []string{"s2"}})
+		state.setAdded(ServiceOptions{Name: "s4", Cmd: "sleep 10", After: []string{"s5"}})
+		state.setAdded(ServiceOptions{Name: "s6", Cmd: "sleep 10", After: []string{}})
+		state.setStopped("s2", true)
+
+		// s1 is no longer defined, and s5 now runs after s4
+		loadedServices := map[string]ServiceOptions{
+			"s5": {Name: "s5", Cmd: "sleep 10", After: []string{"s4"}},
+		}
+		state, err = loadState(stateDir)
+		assert.NoError(t, err)
+		state.restoreServices(loadedServices)
+		assert.Len(t, loadedServices, 2)
+		assert.Contains(t, loadedServices, "s5")
+		assert.Contains(t, loadedServices, "s6")
+
+		// dropped services are forgotten
+		state, err = loadState(stateDir)
+		assert.NoError(t, err)
+		assert.Empty(t, state.state.Stopped)
+		assert.Len(t, state.state.Added, 1)
+		assert.Contains(t, state.state.Added, "s6")
+
+		// sminit starts with the remaining services
+		loadedServices = map[string]ServiceOptions{}
+		state.restoreServices(loadedServices)
+		_, err = NewManager(loadedServices)
+		assert.NoError(t, err)
+	})
+
+	t.Run("handover_lock_test", func(t *testing.T) {
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "sleep 0.5",
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(200 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+
+		// a process that exits while services are handed over is neither reaped nor restarted
+		handoverLock.Lock()
+		time.Sleep(time.Second)
+		assert.True(t, isUnreaped(s1.PID))
+		desc, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, s1.PID, desc.PID)
+		assert.Equal(t, s1.Restarts, desc.Restarts)
+		handoverLock.Unlock()
+
+		time.Sleep(200 * time.Millisecond)
+
+		assert.False(t, isUnreaped(s1.PID))
+		desc, err = manager.Get("s1")
+		assert.NoError(t, err)
+		assert.NotEqual(t, s1.PID, desc.PID)
+
+		assert.NoError(t, manager.Stop("s1"))
+	})
+
+	t.Run("graceful_stop_test", func(t *testing.T) {
+		dir := t.TempDir()
+		logPath := path.Join(dir, "s1.log")
+		script := path.Join(dir, "s1.sh")
+		assert.NoError(t, os.WriteFile(script, []byte(fmt.Sprintf(`#!/bin/sh
+echo start >> %[1]s
+trap 'echo term >> %[1]s; exit 0' TERM
+while true; do sleep 0.1; done
+`, logPath)), 0755))
+		stubborn := path.Join(dir, "s2.sh")
+		assert.NoError(t, os.WriteFile(stubborn, []byte(`#!/bin/sh
+trap '' TERM
+while true; do sleep 0.1; done
+`), 0755))
+
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         script,
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+			"s2": {
+				Name:        "s2",
+				Cmd:         stubborn,
+				Log:         "stdout",
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+				StopTimeout: 300 * time.Millisecond,
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(500 * time.Millisecond)
+
+		// the process handles SIGTERM before the service is started again
+		assert.NoError(t, manager.Restart("s1", false))
+		time.Sleep(500 * time.Millisecond)
+		content, err := os.ReadFile(logPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "start\nterm\nstart\n", string(content))
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Running, s1.Status)
+
+		// a process that ignores SIGTERM is killed once its stop timeout has passed
+		s2, err := manager.Get("s2")
+		assert.NoError(t, err)
+		start := time.Now()
+		assert.NoError(t, manager.Stop("s2"))
+		assert.GreaterOrEqual(t, time.Since(start), 300*time.Millisecond)
+		assert.Less(t, time.Since(start), 2*time.Second)
+		assert.False(t, isUnreaped(s2.PID))
+
+		assert.NoError(t, manager.Stop("s1"))
+	})
+	t.Run("hook_env_test", func(t *testing.T) {
+		dir := t.TempDir()
+		hookPath := path.Join(dir, "hook")
+		hook := path.Join(dir, "hook.sh")
+		assert.NoError(t, os.WriteFile(hook, []byte(fmt.Sprintf(`#!/bin/sh
+echo "$SMINIT_TRIGGER_SERVICE $(nice)" > %s
+`, hookPath)), 0755))
+
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         "false",
+				Log:         "stdout",
+				After:       []string{"s2"},
+				OneShot:     true,
+				HealthCheck: "true",
+				OnFailure:   []string{"s2"},
+			},
+			"s2": {
+				Name:         "s2",
+				Cmd:          "true",
+				Log:          "stdout",
+				After:        []string{},
+				OneShot:      true,
+				HealthCheck:  "true",
+				Nice:         5,
+				ExecStartPre: []string{hook},
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(time.Second)
+
+		// the hook of the triggered run gets the environment added for the run, and the options of the process
+		content, err := os.ReadFile(hookPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "s1 5\n", string(content))
+	})
+	t.Run("output_retry_test", func(t *testing.T) {
+		dir := t.TempDir()
+		// the directory of the log file can not be created while a file is in its place
+		blocked := path.Join(dir, "logs")
+		assert.NoError(t, os.WriteFile(blocked, nil, 0644))
+		logPath := path.Join(blocked, "s1.log")
+		script := path.Join(dir, "s1.sh")
+		assert.NoError(t, os.WriteFile(script, []byte(`#!/bin/sh
+echo hello
+sleep 10
+`), 0755))
+
+		loadedServices := map[string]ServiceOptions{
+			"s1": {
+				Name:        "s1",
+				Cmd:         script,
+				Log:         "file",
+				LogFile:     LogFileOptions{Path: logPath},
+				After:       []string{},
+				OneShot:     false,
+				HealthCheck: "true",
+			},
+		}
+		manager, err := NewManager(loadedServices)
+		assert.NoError(t, err)
+
+		manager.fireServices()
+
+		time.Sleep(300 * time.Millisecond)
+
+		s1, err := manager.Get("s1")
+		assert.NoError(t, err)
+		assert.Equal(t, Failed, s1.Status)
+		assert.Equal(t, ReasonStartError, s1.FailureReason)
+
+		// the output is prepared again when the service is restarted
+		assert.NoError(t, os.Remove(blocked))
+		deadline := time.Now().Add(5 * time.Second)
+		for s1.Status != Running && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+			s1, err = manager.Get("s1")
+			assert.NoError(t, err)
+		}
+		assert.Equal(t, Running, s1.Status)
+
+		time.Sleep(200 * time.Millisecond)
+		content, err := os.ReadFile(logPath)
+		assert.NoError(t, err)
+		assert.Equal(t, "hello\n", string(content))
+
+		assert.NoError(t, manager.Stop("s1"))
+	})
 }
@@ -0,0 +1,297 @@
+package manager
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+// clockTicks is the number of clock ticks per second used by the kernel to report process cpu times in /proc
+const clockTicks = 100
+
+// requestDurationBuckets are the upper bounds in seconds of api request duration histogram buckets
+var requestDurationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// allStatuses are all statuses a service could have, exported as labels of sminit_service_status
+var allStatuses = []Status{Started, Running, Successful, Failed, Pending, Stopped, Disabled, Skipped}
+
+// apiMetrics collects counts and durations of api requests
+type apiMetrics struct {
+	requests  map[requestLabels]int
+	durations map[routeLabels]*histogram
+	mut       sync.Mutex
+}
+
+type requestLabels struct {
+	method string
+	path   string
+	code   int
+}
+
+type routeLabels struct {
+	method string
+	path   string
+}
+
+type histogram struct {
+	// counts are cumulative counts of observations less than or equal to each bucket
+	counts []int
+	sum    float64
+	count  int
+}
+
+// processStats are resource usage statistics of a process read from /proc
+type processStats struct {
+	cpuSeconds float64
+	rssBytes   int64
+}
+
+func newAPIMetrics() *apiMetrics {
+	return &apiMetrics{
+		requests:  map[requestLabels]int{},
+		durations: map[routeLabels]*histogram{},
+	}
+}
+
+// middleware records the count and duration of each request
+func (a *apiMetrics) middleware(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	duration := time.Since(start).Seconds()
+
+	// the route template is used instead of the request path, to keep the number of label values bounded
+	path := c.FullPath()
+	if path == "" {
+		path = "unmatched"
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.requests[requestLabels{method: c.Request.Method, path: path, code: c.Writer.Status()}]++
+
+	route := routeLabels{method: c.Request.Method, path: path}
+	h, ok := a.durations[route]
+	if !ok {
+		h = &histogram{counts: make([]int, len(requestDurationBuckets))}
+		a.durations[route] = h
+	}
+	h.observe(duration)
+}
+
+func (h *histogram) observe(value float64) {
+	for i, bound := range requestDurationBuckets {
+		if value <= bound {
+			h.counts[i]++
+		}
+	}
+	h.sum += value
+	h.count++
+}
+
+func (a *apiMetrics) write(w io.Writer) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	fmt.Fprintln(w, "# HELP sminit_http_requests_total Number of api requests handled by sminit.")
+	fmt.Fprintln(w, "# TYPE sminit_http_requests_total counter")
+	requests := make([]requestLabels, 0, len(a.requests))
+	for labels := range a.requests {
+		requests = append(requests, labels)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		return fmt.Sprint(requests[i]) < fmt.Sprint(requests[j])
+	})
+	for _, labels := range requests {
+		fmt.Fprintf(w, "sminit_http_requests_total{method=%q,path=%q,code=\"%d\"} %d\n", labels.method, labels.path, labels.code, a.requests[labels])
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_http_request_duration_seconds Duration of api requests handled by sminit.")
+	fmt.Fprintln(w, "# TYPE sminit_http_request_duration_seconds histogram")
+	routes := make([]routeLabels, 0, len(a.durations))
+	for labels := range a.durations {
+		routes = append(routes, labels)
+	}
+	sort.Slice(routes, func(i, j int) bool {
+		return fmt.Sprint(routes[i]) < fmt.Sprint(routes[j])
+	})
+	for _, labels := range routes {
+		h := a.durations[labels]
+		for i, bound := range requestDurationBuckets {
+			fmt.Fprintf(w, "sminit_http_request_duration_seconds_bucket{method=%q,path=%q,le=\"%s\"} %d\n", labels.method, labels.path, formatFloat(bound), h.counts[i])
+		}
+		fmt.Fprintf(w, "sminit_http_request_duration_seconds_bucket{method=%q,path=%q,le=\"+Inf\"} %d\n", labels.method, labels.path, h.count)
+		fmt.Fprintf(w, "sminit_http_request_duration_seconds_sum{method=%q,path=%q} %s\n", labels.method, labels.path, formatFloat(h.sum))
+		fmt.Fprintf(w, "sminit_http_request_duration_seconds_count{method=%q,path=%q} %d\n", labels.method, labels.path, h.count)
+	}
+}
+
+// writeServiceMetrics writes the metrics of the given services in prometheus text format
+func writeServiceMetrics(w io.Writer, services []ServiceDesc) {
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].Name < services[j].Name
+	})
+
+	fmt.Fprintln(w, "# HELP sminit_service_status Whether the service is in the status of the status label.")
+	fmt.Fprintln(w, "# TYPE sminit_service_status gauge")
+	for _, service := range services {
+		for _, status := range allStatuses {
+			fmt.Fprintf(w, "sminit_service_status{service=%q,status=%q} %d\n", service.Name, status, boolToInt(service.Status == status))
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_restarts_total Number of times the service process was restarted by sminit.")
+	fmt.Fprintln(w, "# TYPE sminit_service_restarts_total counter")
+	for _, service := range services {
+		fmt.Fprintf(w, "sminit_service_restarts_total{service=%q} %d\n", service.Name, service.Restarts)
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_last_exit_code Exit code of the last process of the service, -1 if it was killed by a signal.")
+	fmt.Fprintln(w, "# TYPE sminit_service_last_exit_code gauge")
+	for _, service := range services {
+		if service.LastExit != nil {
+			fmt.Fprintf(w, "sminit_service_last_exit_code{service=%q} %d\n", service.Name, service.LastExit.Code)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_uptime_seconds Time since the running process of the service was started.")
+	fmt.Fprintln(w, "# TYPE sminit_service_uptime_seconds gauge")
+	for _, service := range services {
+		if service.PID != 0 {
+			fmt.Fprintf(w, "sminit_service_uptime_seconds{service=%q} %s\n", service.Name, formatFloat(time.Since(service.StartedAt).Seconds()))
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_health_check_duration_seconds Duration of the last health check of the service.")
+	fmt.Fprintln(w, "# TYPE sminit_service_health_check_duration_seconds gauge")
+	for _, service := range services {
+		fmt.Fprintf(w, "sminit_service_health_check_duration_seconds{service=%q} %s\n", service.Name, formatFloat(service.HealthCheckDuration.Seconds()))
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_health_check_failures_total Number of failed health checks of the service.")
+	fmt.Fprintln(w, "# TYPE sminit_service_health_check_failures_total counter")
+	for _, service := range services {
+		fmt.Fprintf(w, "sminit_service_health_check_failures_total{service=%q} %d\n", service.Name, service.HealthCheckFailures)
+	}
+
+	stats := map[string]processStats{}
+	for _, service := range services {
+		if service.PID == 0 {
+			continue
+		}
+		if s, err := readProcessStats(service.PID); err == nil {
+			stats[service.Name] = s
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_cpu_seconds_total User and system cpu time of the service process.")
+	fmt.Fprintln(w, "# TYPE sminit_service_cpu_seconds_total counter")
+	for _, service := range services {
+		if s, ok := stats[service.Name]; ok {
+			fmt.Fprintf(w, "sminit_service_cpu_seconds_total{service=%q} %s\n", service.Name, formatFloat(s.cpuSeconds))
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_resident_memory_bytes Resident memory size of the service process.")
+	fmt.Fprintln(w, "# TYPE sminit_service_resident_memory_bytes gauge")
+	for _, service := range services {
+		if s, ok := stats[service.Name]; ok {
+			fmt.Fprintf(w, "sminit_service_resident_memory_bytes{service=%q} %d\n", service.Name, s.rssBytes)
+		}
+	}
+
+	usages := map[string]ResourceUsage{}
+	for _, service := range services {
+		if service.Cgroup == "" {
+			continue
+		}
+		if usage, err := readCgroupUsage(service.Cgroup); err == nil {
+			usages[service.Name] = usage
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_cgroup_cpu_seconds_total Cpu time of all processes in the cgroup of the service.")
+	fmt.Fprintln(w, "# TYPE sminit_service_cgroup_cpu_seconds_total counter")
+	for _, service := range services {
+		if usage, ok := usages[service.Name]; ok {
+			fmt.Fprintf(w, "sminit_service_cgroup_cpu_seconds_total{service=%q} %s\n", service.Name, formatFloat(usage.CPUUsage.Seconds()))
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_cgroup_memory_bytes Memory used by all processes in the cgroup of the service.")
+	fmt.Fprintln(w, "# TYPE sminit_service_cgroup_memory_bytes gauge")
+	for _, service := range services {
+		if usage, ok := usages[service.Name]; ok {
+			fmt.Fprintf(w, "sminit_service_cgroup_memory_bytes{service=%q} %d\n", service.Name, usage.MemoryCurrent)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP sminit_service_cgroup_tasks Number of processes and threads in the cgroup of the service.")
+	fmt.Fprintln(w, "# TYPE sminit_service_cgroup_tasks gauge")
+	for _, service := range services {
+		if usage, ok := usages[service.Name]; ok {
+			fmt.Fprintf(w, "sminit_service_cgroup_tasks{service=%q} %d\n", service.Name, usage.Tasks)
+		}
+	}
+}
+
+// readProcessStats reads cpu time and resident memory of a process from /proc/<pid>/stat
+func readProcessStats(pid int) (processStats, error) {
+	content, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid))
+	if err != nil {
+		return processStats{}, err
+	}
+
+	// the command name is between parentheses and could contain spaces, fields are counted after it
+	stat := string(content)
+	fields := strings.Fields(stat[strings.LastIndex(stat, ")")+1:])
+	// fields[0] is the state, the third field of the file
+	if len(fields) < 22 {
+		return processStats{}, errors.Errorf("unexpected format of /proc/%d/stat", pid)
+	}
+
+	utime, err := strconv.ParseUint(fields[11], 10, 64)
+	if err != nil {
+		return processStats{}, errors.Wrap(err, "invalid utime")
+	}
+	stime, err := strconv.ParseUint(fields[12], 10, 64)
+	if err != nil {
+		return processStats{}, errors.Wrap(err, "invalid stime")
+	}
+	rss, err := strconv.ParseInt(fields[21], 10, 64)
+	if err != nil {
+		return processStats{}, errors.Wrap(err, "invalid rss")
+	}
+
+	return processStats{
+		cpuSeconds: float64(utime+stime) / clockTicks,
+		rssBytes:   rss * int64(os.Getpagesize()),
+	}, nil
+}
+
+func (s *App) metrics(c *gin.Context) {
+	c.Header("Content-Type", "text/plain; version=0.0.4")
+	c.Status(http.StatusOK)
+	writeServiceMetrics(c.Writer, s.Manager.List())
+	s.apiMetrics.write(c.Writer)
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
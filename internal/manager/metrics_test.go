@@ -0,0 +1,67 @@
+package manager
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Run("process_stats", func(t *testing.T) {
+		stats, err := readProcessStats(os.Getpid())
+		assert.NoError(t, err)
+		assert.Greater(t, stats.rssBytes, int64(0))
+		assert.GreaterOrEqual(t, stats.cpuSeconds, float64(0))
+
+		_, err = readProcessStats(0)
+		assert.Error(t, err)
+	})
+
+	t.Run("service_metrics", func(t *testing.T) {
+		buf := bytes.Buffer{}
+		writeServiceMetrics(&buf, []ServiceDesc{
+			{Name: "s2", Status: Failed, Restarts: 3, LastExit: &ExitInfo{Code: 2}, HealthCheckFailures: 4},
+			{Name: "s1", Status: Running, PID: os.Getpid()},
+		})
+		out := buf.String()
+
+		assert.Contains(t, out, `sminit_service_status{service="s1",status="running"} 1`)
+		assert.Contains(t, out, `sminit_service_status{service="s1",status="failed"} 0`)
+		assert.Contains(t, out, `sminit_service_status{service="s2",status="failed"} 1`)
+		assert.Contains(t, out, `sminit_service_restarts_total{service="s2"} 3`)
+		assert.Contains(t, out, `sminit_service_last_exit_code{service="s2"} 2`)
+		assert.NotContains(t, out, `sminit_service_last_exit_code{service="s1"}`)
+		assert.Contains(t, out, `sminit_service_health_check_failures_total{service="s2"} 4`)
+		assert.Contains(t, out, `sminit_service_uptime_seconds{service="s1"}`)
+		assert.Contains(t, out, `sminit_service_cpu_seconds_total{service="s1"}`)
+		assert.Contains(t, out, `sminit_service_resident_memory_bytes{service="s1"}`)
+		assert.NotContains(t, out, `sminit_service_resident_memory_bytes{service="s2"}`)
+	})
+
+	t.Run("api_metrics", func(t *testing.T) {
+		gin.SetMode(gin.TestMode)
+		metrics := newAPIMetrics()
+		router := gin.New()
+		router.Use(metrics.middleware)
+		router.GET("/services/:name", func(c *gin.Context) {
+			c.Status(http.StatusNotFound)
+		})
+
+		for _, name := range []string{"s1", "s2"} {
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/services/"+name, nil))
+		}
+
+		buf := bytes.Buffer{}
+		metrics.write(&buf)
+		out := buf.String()
+
+		assert.Contains(t, out, `sminit_http_requests_total{method="GET",path="/services/:name",code="404"} 2`)
+		assert.Contains(t, out, `sminit_http_request_duration_seconds_bucket{method="GET",path="/services/:name",le="+Inf"} 2`)
+		assert.Contains(t, out, `sminit_http_request_duration_seconds_count{method="GET",path="/services/:name"} 2`)
+	})
+}
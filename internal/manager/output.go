@@ -0,0 +1,89 @@
+package manager
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// serviceOutput receives the output of the processes of a service line by line.
+// each line is kept in the log buffer of the service, then passed to the sink of its log mode.
+type serviceOutput struct {
+	stdout *lineWriter
+	stderr *lineWriter
+	close  func()
+}
+
+// newServiceOutput prepares the output of the service according to its log mode.
+// output is only kept in the log buffer for "null" log, or if log is not set.
+func (s *Service) newServiceOutput() (*serviceOutput, error) {
+	var sink func(stream, line string)
+
+	switch s.log {
+	case LogStdout:
+		logger := s.logger()
+		sink = func(stream, line string) {
+			event := logger.Info()
+			if stream == StreamStderr {
+				event = logger.Error()
+			}
+			event.Str("stream", stream).Int("pid", s.desc().PID).Msg(line)
+		}
+		return s.lineOutput(sink, func() {}), nil
+
+	case LogFile:
+		file, err := newRotatingFile(s.options.LogFile)
+		if err != nil {
+			return nil, err
+		}
+		sink = func(stream, line string) {
+			_, _ = file.Write([]byte(line + "\n"))
+		}
+		return s.lineOutput(sink, func() { _ = file.Close() }), nil
+
+	case LogSyslog:
+		sink, err := newSyslogSink(s.Name, s.options.Syslog)
+		if err != nil {
+			return nil, err
+		}
+		return s.lineOutput(sink.write, sink.close), nil
+
+	case LogPipe:
+		companion, err := s.logCompanion()
+		if err != nil {
+			return nil, err
+		}
+		// the companion is not stopped with the service process, so it does not lose lines across restarts
+		return s.lineOutput(companion.write, func() {}), nil
+
+	case LogNull, "":
+		return s.lineOutput(func(stream, line string) {}, func() {}), nil
+
+	default:
+		return nil, errors.Errorf("unknown log %s", s.log)
+	}
+}
+
+func (s *Service) lineOutput(sink func(stream, line string), close func()) *serviceOutput {
+	emitter := func(stream string) func(line string) {
+		return func(line string) {
+			s.logs.add(LogLine{Time: time.Now(), Stream: stream, Line: line})
+			sink(stream, line)
+		}
+	}
+
+	return &serviceOutput{
+		stdout: newLineWriter(maxLineLength, emitter(StreamStdout)),
+		stderr: newLineWriter(maxLineLength, emitter(StreamStderr)),
+		close:  close,
+	}
+}
+
+// flush emits the partial lines left by a terminated process
+func (o *serviceOutput) flush() {
+	if o.stdout == nil || o.stderr == nil {
+		return
+	}
+	o.stdout.Flush()
+	o.stderr.Flush()
+}
@@ -0,0 +1,267 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// defaultStopTimeout is how long the processes of a service have to terminate after SIGTERM, unless the service sets stop_timeout
+const defaultStopTimeout = 10 * time.Second
+
+// serviceProcess is a process of a service, either started by sminit, or adopted from the sminit instance that re-executed into this one.
+// it is reaped with wait4 rather than exec.Cmd.Wait, so adopted processes are handled like any other process.
+type serviceProcess struct {
+	pid int
+	// stdout and stderr are the read ends of the output pipes of the process, they are nil if its output is discarded
+	stdout *os.File
+	stderr *os.File
+	// cgroup is the path of the cgroup of the process, all processes in it are killed with the process. it is empty if there is none
+	cgroup string
+	// stopTimeout is how long the process has to terminate after SIGTERM when it is stopped, before it is killed
+	stopTimeout time.Duration
+
+	copying sync.WaitGroup
+}
+
+// startProcess starts cmdStr in its own process group with run, and copies its output to output.
+// an error is returned if the options of run could not be applied.
+func startProcess(cmdStr string, output *serviceOutput, run runEnv) (*serviceProcess, error) {
+	cmd, err := run.command(context.Background(), cmdStr)
+	if err != nil {
+		return nil, err
+	}
+	// the process gets its own process group, so signals can be sent to all of its processes
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	process := &serviceProcess{}
+	// the write ends are only needed by the process, they are closed once it is started
+	writers := []*os.File{}
+	defer func() {
+		for _, w := range writers {
+			w.Close()
+		}
+	}()
+
+	if output.stdout != nil && output.stderr != nil {
+		stdout, stdoutWriter, err := os.Pipe()
+		if err != nil {
+			return nil, err
+		}
+		writers = append(writers, stdoutWriter)
+
+		stderr, stderrWriter, err := os.Pipe()
+		if err != nil {
+			stdout.Close()
+			return nil, err
+		}
+		writers = append(writers, stderrWriter)
+
+		process.stdout, process.stderr = stdout, stderr
+		cmd.Stdout, cmd.Stderr = stdoutWriter, stderrWriter
+	}
+
+	err = startCommand(cmd)
+	if err != nil {
+		process.close()
+		return nil, err
+	}
+	process.pid = cmd.Process.Pid
+	_ = cmd.Process.Release()
+
+	process.copyOutput(output)
+	return process, nil
+}
+
+// adoptProcess tracks a running process started by another sminit instance, with the read ends of its output pipes at the given file descriptors.
+// a negative file descriptor means the output of the process is discarded.
+func adoptProcess(pid int, stdoutFd int, stderrFd int, output *serviceOutput) *serviceProcess {
+	process := &serviceProcess{pid: pid}
+	if stdoutFd >= 0 {
+		process.stdout = os.NewFile(uintptr(stdoutFd), fmt.Sprintf("stdout of %d", pid))
+	}
+	if stderrFd >= 0 {
+		process.stderr = os.NewFile(uintptr(stderrFd), fmt.Sprintf("stderr of %d", pid))
+	}
+
+	process.copyOutput(output)
+	return process
+}
+
+// copyOutput copies the output of the process to output until its pipes are closed.
+// if output discards lines, the pipes are still read so the process does not block writing to them.
+func (p *serviceProcess) copyOutput(output *serviceOutput) {
+	copyPipe := func(r *os.File, w *lineWriter) {
+		if r == nil {
+			return
+		}
+		var dst io.Writer = io.Discard
+		if w != nil {
+			dst = w
+		}
+
+		p.copying.Add(1)
+		go func() {
+			defer p.copying.Done()
+			_, _ = io.Copy(dst, r)
+		}()
+	}
+
+	copyPipe(p.stdout, output.stdout)
+	copyPipe(p.stderr, output.stderr)
+}
+
+// wait waits for the process to terminate, and for its output to be copied. the process is stopped if ctx is cancelled first.
+func (p *serviceProcess) wait(ctx context.Context) (syscall.WaitStatus, error) {
+	exited := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			p.stop(exited)
+		case <-exited:
+		}
+	}()
+
+	var status syscall.WaitStatus
+	var err error
+	// the process is waited for without being reaped, then reaped unless it is being handed over,
+	// so a process that exits while it is handed over is reaped by the new sminit instance instead
+	for {
+		err = unix.Waitid(unix.P_PID, p.pid, &unix.Siginfo{}, unix.WEXITED|unix.WNOWAIT, nil)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err == nil {
+		handoverLock.RLock()
+		for {
+			_, err = syscall.Wait4(p.pid, &status, 0, nil)
+			if err != syscall.EINTR {
+				break
+			}
+		}
+		handoverLock.RUnlock()
+	}
+	close(exited)
+	// the processes left in the cgroup are killed before the process is considered stopped
+	<-stopped
+	if err != nil {
+		p.close()
+		return status, err
+	}
+
+	p.copying.Wait()
+	p.close()
+	return status, nil
+}
+
+// isUnreaped returns true if the process with the given pid is a child of sminit that has not been reaped, whether it is running or has exited
+func isUnreaped(pid int) bool {
+	err := unix.Waitid(unix.P_PID, pid, &unix.Siginfo{}, unix.WEXITED|unix.WNOHANG|unix.WNOWAIT, nil)
+	return err == nil
+}
+
+// stop sends SIGTERM to the process group of the process, and kills it if the process has not exited within its stop timeout.
+// exited should be closed once the process has exited.
+func (p *serviceProcess) stop(exited <-chan struct{}) {
+	_ = signalGroup(p.pid, syscall.SIGTERM)
+
+	timer := time.NewTimer(p.stopTimeout)
+	defer timer.Stop()
+	select {
+	case <-exited:
+		// processes left in the cgroup are not waited for
+		if p.cgroup != "" {
+			_ = killCgroup(p.cgroup)
+		}
+	case <-timer.C:
+		_ = p.kill()
+	}
+}
+
+// signalGroup sends sig to the process group led by the process with the given pid,
+// or only to the process if it does not lead a process group
+func signalGroup(pid int, sig syscall.Signal) error {
+	err := syscall.Kill(-pid, sig)
+	if err == syscall.ESRCH {
+		return syscall.Kill(pid, sig)
+	}
+	return err
+}
+
+// kill kills the process group of the process, and all processes in its cgroup if it has one
+func (p *serviceProcess) kill() error {
+	err := signalGroup(p.pid, syscall.SIGKILL)
+	if p.cgroup != "" {
+		if cgroupErr := killCgroup(p.cgroup); cgroupErr != nil && err == nil {
+			err = cgroupErr
+		}
+	}
+	return err
+}
+
+func (p *serviceProcess) close() {
+	if p.stdout != nil {
+		p.stdout.Close()
+	}
+	if p.stderr != nil {
+		p.stderr.Close()
+	}
+}
+
+// inherit makes the output pipes of the process inherited by the sminit instance this one re-executes into,
+// and returns their file descriptors, -1 for pipes the process does not have.
+func (p *serviceProcess) inherit() (stdoutFd int, stderrFd int, err error) {
+	stdoutFd, stderrFd = -1, -1
+	if p.stdout != nil {
+		if stdoutFd, err = inheritFd(p.stdout); err != nil {
+			return -1, -1, err
+		}
+	}
+	if p.stderr != nil {
+		if stderrFd, err = inheritFd(p.stderr); err != nil {
+			return -1, -1, err
+		}
+	}
+	return stdoutFd, stderrFd, nil
+}
+
+// inheritFd clears the close-on-exec flag of the file descriptor of f, without changing its blocking mode, and returns it
+func inheritFd(f interface {
+	SyscallConn() (syscall.RawConn, error)
+}) (int, error) {
+	conn, err := f.SyscallConn()
+	if err != nil {
+		return -1, err
+	}
+
+	fd := -1
+	var fcntlErr error
+	err = conn.Control(func(ptr uintptr) {
+		fd = int(ptr)
+		_, fcntlErr = unix.FcntlInt(ptr, unix.F_SETFD, 0)
+	})
+	if err != nil {
+		return -1, err
+	}
+	return fd, fcntlErr
+}
+
+// exitError describes an unsuccessful termination of a process, it returns nil if the process exited with status 0
+func exitError(status syscall.WaitStatus) error {
+	if status.Signaled() {
+		return fmt.Errorf("signal: %s", status.Signal())
+	}
+	if status.ExitStatus() != 0 {
+		return fmt.Errorf("exit status %d", status.ExitStatus())
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// reexecStateEnv is the environment variable holding the path of the state handed over by the sminit instance that re-executed into this one
+	reexecStateEnv = "SMINIT_REEXEC_STATE"
+	// reexecStatePath is the path the state is handed over in
+	reexecStatePath = "/run/sminit/reexec.json"
+)
+
+// handoverLock is held while services are handed over to the sminit instance this one re-executes into.
+// processes of services are started and reaped holding it for reading, so the handed over processes are exactly the tracked ones.
+var handoverLock sync.RWMutex
+
+// reexecState is the state of an sminit instance handed over to the instance it re-executes into
+type reexecState struct {
+	// HTTPListener and SocketListener are the file descriptors of the listeners of sminit
+	HTTPListener   int
+	SocketListener int
+	Services       []adoptedService
+}
+
+// adoptedService is a service, with its running process if any, handed over to the instance sminit re-executes into
+type adoptedService struct {
+	ServiceDesc
+	// Stdout and Stderr are the file descriptors of the read ends of the output pipes of the process, -1 if its output is discarded
+	Stdout int
+	Stderr int
+}
+
+// IsReexec returns true if this sminit instance was executed by a running sminit instance to replace it.
+// such an instance should not daemonize, it already runs as the daemon.
+func IsReexec() bool {
+	return os.Getenv(reexecStateEnv) != ""
+}
+
+// readReexecState reads the state handed over by the sminit instance that re-executed into this one
+func readReexecState() (*reexecState, error) {
+	statePath := os.Getenv(reexecStateEnv)
+	// services should not get the environment variable
+	os.Unsetenv(reexecStateEnv)
+
+	content, err := os.ReadFile(statePath)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not read handed over state %s", statePath)
+	}
+	_ = os.Remove(statePath)
+
+	state := reexecState{}
+	if err := json.Unmarshal(content, &state); err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal handed over state %s", statePath)
+	}
+	return &state, nil
+}
+
+// fileListener returns the listener at the given inherited file descriptor
+func fileListener(fd int, name string) (net.Listener, error) {
+	file := os.NewFile(uintptr(fd), name)
+	defer file.Close()
+
+	listener, err := net.FileListener(file)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not use inherited %s listener", name)
+	}
+	return listener, nil
+}
+
+// prepareReexec stops all changes to services, and prepares the state to be handed over to a new sminit instance.
+// it returns a function that replaces this instance with the sminit binary found at the path of the running binary,
+// which could have been upgraded. the returned function only returns if it fails.
+func (a *App) prepareReexec() (func() error, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, errors.Wrapf(ErrSminitInternalError, "could not find sminit executable. %s", err.Error())
+	}
+	// the running binary is reported as deleted if it was replaced by the upgrade
+	executable = strings.TrimSuffix(executable, " (deleted)")
+	if _, err := os.Stat(executable); err != nil {
+		return nil, errors.Wrapf(ErrSminitInternalError, "could not find sminit executable. %s", err.Error())
+	}
+
+	// services are not started or stopped by users while they are handed over, and processes are neither started nor reaped.
+	// the locks are kept if sminit re-executes, processes that exit meanwhile are reaped by the new instance.
+	a.Manager.ops.Lock()
+	handoverLock.Lock()
+	unlock := func() {
+		handoverLock.Unlock()
+		a.Manager.ops.Unlock()
+	}
+
+	state, files, err := a.handover()
+	if err != nil {
+		unlock()
+		return nil, errors.Wrapf(ErrSminitInternalError, "could not prepare state to hand over. %s", err.Error())
+	}
+
+	content, err := json.Marshal(state)
+	if err != nil {
+		unlock()
+		return nil, errors.Wrapf(ErrSminitInternalError, "could not marshal state to hand over. %s", err.Error())
+	}
+	err = os.WriteFile(reexecStatePath, content, 0600)
+	if err != nil {
+		unlock()
+		return nil, errors.Wrapf(ErrSminitInternalError, "could not write state to hand over. %s", err.Error())
+	}
+
+	return func() error {
+		// log companions are not handed over, they read the remaining lines and are started again by the new instance
+		for _, service := range a.Manager.getServicesMap() {
+			service.stopLogCompanion()
+		}
+
+		SminitLog.Info().Msgf("re-executing %s", executable)
+		env := append(os.Environ(), fmt.Sprintf("%s=%s", reexecStateEnv, reexecStatePath))
+		err := syscall.Exec(executable, os.Args, env)
+
+		// files should be kept open until sminit re-executes, otherwise they are closed when garbage collected
+		for _, f := range files {
+			f.Close()
+		}
+		_ = os.Remove(reexecStatePath)
+		unlock()
+		return errors.Wrapf(ErrSminitInternalError, "could not re-execute %s. %s", executable, err.Error())
+	}, nil
+}
+
+// handover returns the state to hand over, and the files that should be kept open until sminit re-executes
+func (a *App) handover() (reexecState, []*os.File, error) {
+	state := reexecState{}
+	files := []*os.File{}
+
+	listeners := []struct {
+		listener net.Listener
+		fd       *int
+	}{
+		{a.httpListener, &state.HTTPListener},
+		{a.Listener, &state.SocketListener},
+	}
+	for _, l := range listeners {
+		// the file of a listener is a duplicate of its file descriptor, so it does not affect the listener
+		file, err := l.listener.(interface{ File() (*os.File, error) }).File()
+		if err != nil {
+			return state, files, errors.Wrap(err, "could not get listener file")
+		}
+		files = append(files, file)
+
+		*l.fd, err = inheritFd(file)
+		if err != nil {
+			return state, files, errors.Wrap(err, "could not make listener inheritable")
+		}
+	}
+
+	for _, service := range a.Manager.getServicesMap() {
+		adopted := adoptedService{ServiceDesc: service.desc(), Stdout: -1, Stderr: -1}
+
+		service.mut.RLock()
+		process := service.process
+		service.mut.RUnlock()
+
+		// the process could have been restarted since the service was described, or reaped before the handover lock was taken
+		adopted.PID = 0
+		if process != nil && isUnreaped(process.pid) {
+			adopted.PID = process.pid
+			var err error
+			adopted.Stdout, adopted.Stderr, err = process.inherit()
+			if err != nil {
+				return state, files, errors.Wrapf(err, "could not make output of service %s inheritable", service.Name)
+			}
+		}
+
+		state.Services = append(state.Services, adopted)
+	}
+
+	return state, files, nil
+}
+
+// adoptServices restores the state of services handed over by the sminit instance that re-executed into this one.
+// running processes are tracked again once services are fired, processes of services that are no longer defined are killed.
+func (m *Manager) adoptServices(services []adoptedService) {
+	for _, adopted := range services {
+		service, ok := m.getService(adopted.Name)
+		if !ok {
+			if adopted.PID != 0 {
+				SminitLog.Info().Msgf("killing process %d of service %s that is no longer defined", adopted.PID, adopted.Name)
+				process := adoptProcess(adopted.PID, adopted.Stdout, adopted.Stderr, &serviceOutput{})
+				_ = process.kill()
+				go process.wait(context.Background())
+			}
+			continue
+		}
+
+		service.adopt(adopted)
+	}
+}
+
+func (s *Service) adopt(adopted adoptedService) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	s.Status = adopted.Status
+	s.lastChange = adopted.LastChange
+	s.restarts = adopted.Restarts
+	s.failureReason = adopted.FailureReason
+	s.lastExit = adopted.LastExit
+	s.lastReload = adopted.LastReload
+	s.healthCheckDuration = adopted.HealthCheckDuration
+	s.healthCheckFailures = adopted.HealthCheckFailures
+	s.lastRun = adopted.LastRun
+
+	if adopted.PID != 0 {
+		s.adopted = &adopted
+		return
+	}
+
+	// a service without a process that has been started was restarting when it was handed over, it is started again once eligible
+	finished := s.Status == Successful && s.oneShot
+	if s.Status != Stopped && s.Status != Disabled && !finished {
+		s.Status = Pending
+	}
+}
+
+// takeAdopted returns the adopted process of the service, if any, and clears it so it is only tracked once
+func (s *Service) takeAdopted() *adoptedService {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	adopted := s.adopted
+	s.adopted = nil
+	return adopted
+}
+
+func (s *Service) hasAdopted() bool {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	return s.adopted != nil
+}
+
+// restoreStartTime sets the start time of the running process to the time it was started by the sminit instance that re-executed into this one
+func (s *Service) restoreStartTime(startedAt time.Time) {
+	s.mut.Lock()
+	s.startedAt = startedAt
+	s.mut.Unlock()
+}
@@ -0,0 +1,101 @@
+package manager
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// reloadTimeout is the maximum time a reload command is allowed to run
+const reloadTimeout = 30 * time.Second
+
+// ReloadInfo describes the last reload of a service
+type ReloadInfo struct {
+	Time time.Time
+	// Error is empty if the reload succeeded
+	Error string
+}
+
+// Reload makes a running service re-read its configuration, by sending it its reload signal, or running its reload command.
+// The status of the service is not changed, even if the reload fails.
+func (m *Manager) Reload(name string) error {
+	service, ok := m.getService(name)
+	if !ok {
+		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	reload := service.options.Reload
+	if reload == "" {
+		return errors.Wrapf(ErrBadRequest, "service %s does not define a reload command or signal", name)
+	}
+
+	desc := service.desc()
+	if desc.Status != Running || desc.PID == 0 {
+		return errors.Wrapf(ErrBadRequest, "service %s is not running", name)
+	}
+
+	err := runReload(reload, desc.PID)
+	service.recordReload(err)
+	if err != nil {
+		SminitLog.Error().Msgf("failed to reload service %s. %s", name, err.Error())
+		return errors.Wrapf(ErrReloadFailed, "failed to reload service %s. %s", name, err.Error())
+	}
+
+	SminitLog.Info().Msgf("service %s is reloaded", name)
+	return nil
+}
+
+// runReload sends the reload signal to pid if reload is a signal name, otherwise it runs reload as a command.
+// The command gets the pid of the service process in MAINPID environment variable.
+func runReload(reload string, pid int) error {
+	if isReloadSignal(reload) {
+		sig, err := ParseSignal(reload)
+		if err != nil {
+			return err
+		}
+		return syscall.Kill(pid, sig)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reloadTimeout)
+	defer cancel()
+
+	splittedCmd := strings.Split(reload, " ")
+	cmd := exec.CommandContext(ctx, splittedCmd[0], splittedCmd[1:]...)
+	cmd.Env = append(os.Environ(), fmt.Sprintf("MAINPID=%d", pid))
+
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if len(output) > 0 {
+			return errors.Wrapf(err, "%s", strings.TrimSpace(string(output)))
+		}
+		return err
+	}
+	return nil
+}
+
+// isReloadSignal returns true if reload is a signal name rather than a command
+func isReloadSignal(reload string) bool {
+	return strings.HasPrefix(reload, "SIG")
+}
+
+func (s *Service) recordReload(err error) {
+	reload := ReloadInfo{
+		Time: time.Now(),
+	}
+	if err != nil {
+		reload.Error = err.Error()
+	}
+
+	s.mut.Lock()
+	s.lastReload = &reload
+	pid := s.pid
+	s.mut.Unlock()
+
+	s.events.publish(Event{Time: reload.Time, Type: EventReload, Service: s.Name, PID: pid, Error: reload.Error})
+}
@@ -0,0 +1,212 @@
+package manager
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// ScheduleOptions makes a oneshot service run periodically, either at times matching a cron expression, or at a fixed interval
+type ScheduleOptions struct {
+	// Cron is a five field cron expression like "*/15 * * * *", or a shortcut like "@daily"
+	Cron string `yaml:"cron,omitempty"`
+	// Every is the interval between runs, like 15m
+	Every time.Duration `yaml:"every,omitempty"`
+	// Jitter is the maximum random delay added to each run
+	Jitter time.Duration `yaml:"jitter,omitempty"`
+	// CatchUp runs the service once when sminit starts, if a run was missed while sminit was not running
+	CatchUp bool `yaml:"catch_up,omitempty"`
+	// SkipIfRunning skips a run if the previous run is still going, instead of stopping it and starting the service again
+	SkipIfRunning bool `yaml:"skip_if_running,omitempty"`
+}
+
+// UnmarshalYAML accepts a cron expression as a plain string, in addition to the full schedule options
+func (s *ScheduleOptions) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*s = ScheduleOptions{Cron: value.Value}
+		return nil
+	}
+
+	type plain ScheduleOptions
+	return value.Decode((*plain)(s))
+}
+
+// IsSet returns true if the service has a schedule
+func (s ScheduleOptions) IsSet() bool {
+	return s.Cron != "" || s.Every != 0
+}
+
+func (s ScheduleOptions) validate() error {
+	if s.Cron != "" && s.Every != 0 {
+		return errors.New("only one of cron and every should be set")
+	}
+	if s.Every < 0 || s.Jitter < 0 {
+		return errors.New("every and jitter should not be negative")
+	}
+	if s.Cron != "" {
+		if _, err := parseCron(s.Cron); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// next returns the time of the first run after t, without jitter
+func (s ScheduleOptions) next(t time.Time) time.Time {
+	if s.Every != 0 {
+		return t.Add(s.Every)
+	}
+
+	// the expression is validated when the service is loaded
+	cron, err := parseCron(s.Cron)
+	if err != nil {
+		return time.Time{}
+	}
+	return cron.next(t)
+}
+
+// anchor returns the time the first run after now is computed from. intervals are counted from the last run if there was one,
+// so restarting sminit does not postpone runs.
+func (s ScheduleOptions) anchor(lastRun time.Time, now time.Time) time.Time {
+	if s.Every == 0 || lastRun.IsZero() || lastRun.After(now) {
+		return now
+	}
+	missed := now.Sub(lastRun) / s.Every
+	return lastRun.Add(missed * s.Every)
+}
+
+// scheduleRoutine starts the scheduled service with the given name whenever a run is due, until ctx is cancelled
+func (m *Manager) scheduleRoutine(ctx context.Context, name string) {
+	service, ok := m.getService(name)
+	if !ok {
+		return
+	}
+	schedule := service.options.Schedule
+
+	now := time.Now()
+	lastRun := service.desc().LastRun
+	from := schedule.anchor(lastRun, now)
+	if schedule.CatchUp && !lastRun.IsZero() {
+		if missed := schedule.next(lastRun); !missed.IsZero() && missed.Before(now) {
+			service.logger().Info().Msgf("service %s missed a run at %s, catching up", name, missed.Format(time.RFC3339))
+			m.runScheduled(service)
+			from = now
+		}
+	}
+
+	for {
+		next := schedule.next(from)
+		if next.IsZero() {
+			service.logger().Error().Msgf("service %s schedule has no next run", name)
+			service.setNextRun(time.Time{})
+			return
+		}
+		from = next
+
+		if schedule.Jitter > 0 {
+			next = next.Add(time.Duration(rand.Int63n(int64(schedule.Jitter))))
+		}
+		service.setNextRun(next)
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		m.runScheduled(service)
+	}
+}
+
+// runScheduled starts a run of the scheduled service, unless it was stopped or disabled by the user
+func (m *Manager) runScheduled(service *Service) {
+	m.ops.Lock()
+	defer m.ops.Unlock()
+
+	status := service.desc().Status
+	if status == Stopped || status == Disabled {
+		service.logger().Debug().Msgf("skipping scheduled run of service %s, it is %s", service.Name, status)
+		return
+	}
+
+	if status == Started || status == Running {
+		if service.options.Schedule.SkipIfRunning {
+			service.logger().Info().Msgf("skipping scheduled run of service %s, the previous run is still going", service.Name)
+			return
+		}
+		service.stop()
+	}
+
+	service.logger().Info().Msgf("starting scheduled run of service %s", service.Name)
+	service.setRunDue()
+	service.changeStatus(Pending)
+
+	if m.isEligibleToRun(service.Name) {
+		service.startSignal <- true
+	}
+}
+
+// startSchedules starts the timers of all scheduled services
+func (m *Manager) startSchedules() {
+	for name, service := range m.getServicesMap() {
+		m.startSchedule(name, service)
+	}
+}
+
+func (m *Manager) startSchedule(name string, service *Service) {
+	if !service.options.Schedule.IsSet() {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	service.mut.Lock()
+	service.cancelSchedule = cancel
+	service.mut.Unlock()
+
+	go m.scheduleRoutine(ctx, name)
+}
+
+// stopSchedule stops the timer of the service, if it is scheduled
+func (s *Service) stopSchedule() {
+	s.mut.Lock()
+	cancel := s.cancelSchedule
+	s.cancelSchedule = nil
+	s.mut.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (s *Service) setNextRun(t time.Time) {
+	s.mut.Lock()
+	s.nextRun = t
+	s.mut.Unlock()
+}
+
+func (s *Service) setLastRun(t time.Time) {
+	s.mut.Lock()
+	s.lastRun = t
+	s.mut.Unlock()
+}
+
+func (s *Service) setRunDue() {
+	s.mut.Lock()
+	s.runDue = true
+	s.mut.Unlock()
+}
+
+// takeRunDue returns true if a run of the scheduled service is due, and clears it so the run only happens once
+func (s *Service) takeRunDue() bool {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	due := s.runDue
+	s.runDue = false
+	return due
+}
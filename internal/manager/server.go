@@ -1,14 +1,16 @@
 package manager
 
 import (
-	"errors"
-	"fmt"
+	"encoding/json"
 	"net/http"
 	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
 var (
@@ -16,41 +18,75 @@ var (
 	Port    = 8080
 )
 
+const (
+	// ErrCodeBadRequest is the error code of requests that could not be fulfilled because of the request itself
+	ErrCodeBadRequest = "bad_request"
+	// ErrCodeReloadFailed is the error code of reload requests where the service failed to reload
+	ErrCodeReloadFailed = "reload_failed"
+	// ErrCodeInternal is the error code of requests that failed because of an error inside sminit
+	ErrCodeInternal = "internal_error"
+)
+
+// ErrorResponse is the body of every failed request
+type ErrorResponse struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 func (s *App) startHTTPServer() error {
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.New()
 	router.Use(
 		gin.Recovery(),
 	)
+	if s.apiMetrics != nil {
+		router.Use(s.apiMetrics.middleware)
+		router.GET("/metrics", s.metrics)
+	}
 
 	router.POST("/services/:name", s.add)
 	router.DELETE("/services/:name", s.delete)
 	router.PUT("/services/:name/start", s.start)
 	router.PUT("/services/:name/stop", s.stop)
+	router.PUT("/services/:name/restart", s.restart)
+	router.PUT("/services/:name/signal", s.signal)
+	router.PUT("/services/:name/reload", s.reload)
+	router.PUT("/services/:name/enable", s.enable)
+	router.PUT("/services/:name/disable", s.disable)
 	router.GET("/services", s.list)
+	router.GET("/services/:name", s.get)
+	router.GET("/services/:name/logs", s.logs)
+	router.GET("/events", s.events)
+	router.PUT("/reexec", s.reexec)
 
-	err := router.Run(fmt.Sprintf("%s:%d", Address, Port))
+	err := router.RunListener(s.httpListener)
 	return err
 }
 
+// respondError writes err to the response as an ErrorResponse, with a status code matching the kind of err
+func respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrBadRequest):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Code: ErrCodeBadRequest, Message: err.Error()})
+
+	case errors.Is(err, ErrReloadFailed):
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: ErrCodeReloadFailed, Message: err.Error()})
+
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Code: ErrCodeInternal, Message: err.Error()})
+	}
+}
+
 func (s *App) start(c *gin.Context) {
 	serviceName, ok := c.Params.Get("name")
 	if !ok {
-		c.Status(http.StatusBadRequest)
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
 		return
 	}
 
 	err := s.Manager.Start(serviceName)
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrBadRequest):
-			c.String(http.StatusBadRequest, err.Error())
-
-		case errors.Is(err, ErrSminitInternalError):
-			c.String(http.StatusInternalServerError, err.Error())
-		default:
-			c.String(http.StatusInternalServerError, err.Error())
-		}
+		respondError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
@@ -59,21 +95,67 @@ func (s *App) start(c *gin.Context) {
 func (s *App) stop(c *gin.Context) {
 	serviceName, ok := c.Params.Get("name")
 	if !ok {
-		c.Status(http.StatusBadRequest)
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
 		return
 	}
 
 	err := s.Manager.Stop(serviceName)
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrBadRequest):
-			c.String(http.StatusBadRequest, err.Error())
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
 
-		case errors.Is(err, ErrSminitInternalError):
-			c.String(http.StatusInternalServerError, err.Error())
-		default:
-			c.String(http.StatusInternalServerError, err.Error())
-		}
+func (s *App) restart(c *gin.Context) {
+	serviceName, ok := c.Params.Get("name")
+	if !ok {
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
+		return
+	}
+
+	withDependents := c.Query("with_dependents") == "true"
+
+	err := s.Manager.Restart(serviceName, withDependents)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (s *App) signal(c *gin.Context) {
+	serviceName, ok := c.Params.Get("name")
+	if !ok {
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
+		return
+	}
+
+	sig, err := ParseSignal(c.DefaultQuery("signal", "SIGTERM"))
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	group := c.Query("group") == "true"
+
+	err = s.Manager.Signal(serviceName, sig, group)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (s *App) reload(c *gin.Context) {
+	serviceName, ok := c.Params.Get("name")
+	if !ok {
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
+		return
+	}
+
+	err := s.Manager.Reload(serviceName)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
@@ -82,21 +164,13 @@ func (s *App) stop(c *gin.Context) {
 func (s *App) delete(c *gin.Context) {
 	serviceName, ok := c.Params.Get("name")
 	if !ok {
-		c.Status(http.StatusBadRequest)
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
 		return
 	}
 
 	err := s.Manager.Delete(serviceName)
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrBadRequest):
-			c.String(http.StatusBadRequest, err.Error())
-
-		case errors.Is(err, ErrSminitInternalError):
-			c.String(http.StatusInternalServerError, err.Error())
-		default:
-			c.String(http.StatusInternalServerError, err.Error())
-		}
+		respondError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
@@ -105,7 +179,7 @@ func (s *App) delete(c *gin.Context) {
 func (s *App) add(c *gin.Context) {
 	serviceName, ok := c.Params.Get("name")
 	if !ok {
-		c.Status(http.StatusBadRequest)
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
 		return
 	}
 
@@ -113,27 +187,19 @@ func (s *App) add(c *gin.Context) {
 	path := path.Join(ServiceDefinitionDir, fileName)
 	file, err := os.Open(path)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "could not open file at %s. %s", path, err.Error())
+		respondError(c, errors.Wrapf(ErrSminitInternalError, "could not open file at %s. %s", path, err.Error()))
 		return
 	}
 
 	opts, err := ReadService(file, serviceName)
 	if err != nil {
-		c.String(http.StatusInternalServerError, "could not load service %s. %s", serviceName, err.Error())
+		respondError(c, errors.Wrapf(ErrSminitInternalError, "could not load service %s. %s", serviceName, err.Error()))
 		return
 	}
 
 	err = s.Manager.Add(opts)
 	if err != nil {
-		switch {
-		case errors.Is(err, ErrBadRequest):
-			c.String(http.StatusBadRequest, err.Error())
-
-		case errors.Is(err, ErrSminitInternalError):
-			c.String(http.StatusInternalServerError, err.Error())
-		default:
-			c.String(http.StatusInternalServerError, err.Error())
-		}
+		respondError(c, err)
 		return
 	}
 	c.Status(http.StatusOK)
@@ -143,3 +209,188 @@ func (s *App) list(c *gin.Context) {
 	services := s.Manager.List()
 	c.JSON(http.StatusOK, services)
 }
+
+func (s *App) get(c *gin.Context) {
+	serviceName, ok := c.Params.Get("name")
+	if !ok {
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
+		return
+	}
+
+	logLines := 10
+	if lines, ok := c.GetQuery("lines"); ok {
+		n, err := strconv.Atoi(lines)
+		if err != nil {
+			respondError(c, errors.Wrapf(ErrBadRequest, "invalid lines query parameter %s", lines))
+			return
+		}
+		logLines = n
+	}
+
+	service, err := s.Manager.Details(serviceName, logLines)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, service)
+}
+
+// logs writes the log lines of a service as a stream of json objects, one per line.
+// if follow query parameter is true, new lines are streamed until the client disconnects.
+func (s *App) logs(c *gin.Context) {
+	serviceName, ok := c.Params.Get("name")
+	if !ok {
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
+		return
+	}
+
+	tail := -1
+	if tailQuery, ok := c.GetQuery("tail"); ok {
+		n, err := strconv.Atoi(tailQuery)
+		if err != nil {
+			respondError(c, errors.Wrapf(ErrBadRequest, "invalid tail query parameter %s", tailQuery))
+			return
+		}
+		tail = n
+	}
+
+	since := time.Time{}
+	if sinceQuery, ok := c.GetQuery("since"); ok {
+		t, err := ParseSince(sinceQuery)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		since = t
+	}
+
+	if c.Query("follow") != "true" {
+		lines, err := s.Manager.Logs(serviceName, tail, since)
+		if err != nil {
+			respondError(c, err)
+			return
+		}
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		writeJSONLines(c, lines)
+		return
+	}
+
+	lines, ch, cancel, err := s.Manager.FollowLogs(serviceName, tail, since)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	defer cancel()
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	writeJSONLines(c, lines)
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case line := <-ch:
+			writeJSONLines(c, []LogLine{line})
+			c.Writer.Flush()
+		}
+	}
+}
+
+func (s *App) enable(c *gin.Context) {
+	serviceName, ok := c.Params.Get("name")
+	if !ok {
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
+		return
+	}
+
+	err := s.Manager.Enable(serviceName)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (s *App) disable(c *gin.Context) {
+	serviceName, ok := c.Params.Get("name")
+	if !ok {
+		respondError(c, errors.Wrap(ErrBadRequest, "service name is required"))
+		return
+	}
+
+	err := s.Manager.Disable(serviceName)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	c.Status(http.StatusOK)
+}
+
+func (s *App) reexec(c *gin.Context) {
+	execute, err := s.prepareReexec()
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+
+	// the response is complete before sminit re-executes, since the connection is closed by the exec
+	c.Header("Content-Length", "0")
+	c.Status(http.StatusOK)
+	c.Writer.WriteHeaderNow()
+	c.Writer.Flush()
+
+	err = execute()
+	SminitLog.Error().Msg(err.Error())
+}
+
+func (s *App) events(c *gin.Context) {
+	// events of deleted services are kept, so the service name is not checked against tracked services
+	serviceName := c.Query("service")
+
+	if c.Query("follow") != "true" {
+		c.Header("Content-Type", "application/x-ndjson")
+		c.Status(http.StatusOK)
+		writeJSONLines(c, s.Manager.Events(serviceName))
+		return
+	}
+
+	events, ch, cancel := s.Manager.FollowEvents(serviceName)
+	defer cancel()
+
+	c.Header("Content-Type", "application/x-ndjson")
+	c.Status(http.StatusOK)
+	writeJSONLines(c, events)
+	c.Writer.Flush()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case event := <-ch:
+			writeJSONLines(c, []Event{event})
+			c.Writer.Flush()
+		}
+	}
+}
+
+// ParseSince parses since, which is either an RFC3339 time, or a duration before now like 10m
+func ParseSince(since string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(since)
+	if err != nil {
+		return time.Time{}, errors.Wrapf(ErrBadRequest, "invalid since %s, should be an RFC3339 time or a duration", since)
+	}
+	return time.Now().Add(-d), nil
+}
+
+func writeJSONLines[T any](c *gin.Context, values []T) {
+	encoder := json.NewEncoder(c.Writer)
+	for _, value := range values {
+		_ = encoder.Encode(value)
+	}
+}
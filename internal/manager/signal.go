@@ -0,0 +1,59 @@
+package manager
+
+import (
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// ParseSignal converts a signal name like SIGHUP or HUP, or a signal number, to a signal
+func ParseSignal(name string) (syscall.Signal, error) {
+	if num, err := strconv.Atoi(name); err == nil {
+		if unix.SignalName(syscall.Signal(num)) == "" {
+			return 0, errors.Wrapf(ErrBadRequest, "unknown signal %s", name)
+		}
+		return syscall.Signal(num), nil
+	}
+
+	name = strings.ToUpper(name)
+	if !strings.HasPrefix(name, "SIG") {
+		name = "SIG" + name
+	}
+
+	sig := unix.SignalNum(name)
+	if sig == 0 {
+		return 0, errors.Wrapf(ErrBadRequest, "unknown signal %s", name)
+	}
+	return sig, nil
+}
+
+// Signal sends sig to the main process of a service, or to its whole process group if group is true.
+// The status of the service is not changed.
+func (m *Manager) Signal(name string, sig syscall.Signal, group bool) error {
+	service, ok := m.getService(name)
+	if !ok {
+		return errors.Wrapf(ErrBadRequest, "there is no tracked service with name %s", name)
+	}
+
+	pid := service.desc().PID
+	if pid == 0 {
+		return errors.Wrapf(ErrBadRequest, "service %s has no running process", name)
+	}
+
+	// service processes are started in their own process group, with a process group id equal to their pid
+	target := pid
+	if group {
+		target = -pid
+	}
+
+	err := syscall.Kill(target, sig)
+	if err != nil {
+		return errors.Wrapf(ErrSminitInternalError, "failed to send %s to service %s. %s", unix.SignalName(sig), name, err.Error())
+	}
+
+	SminitLog.Info().Msgf("sent %s to service %s", unix.SignalName(sig), name)
+	return nil
+}
@@ -1,6 +1,9 @@
 package manager
 
 import (
+	"fmt"
+	"io"
+	"log/syslog"
 	"net"
 	"os"
 	"os/signal"
@@ -15,6 +18,22 @@ import (
 type App struct {
 	Manager  *Manager
 	Listener net.Listener
+
+	// httpListener is the listener of the http server
+	httpListener net.Listener
+
+	// apiMetrics is nil unless the metrics endpoint is enabled
+	apiMetrics *apiMetrics
+}
+
+// AppOptions configures the sminit daemon
+type AppOptions struct {
+	// Metrics enables the /metrics endpoint, exposing service and api metrics in prometheus text format
+	Metrics bool
+	// StateDir is the directory sminit keeps its journal and the intent of the user in, DefaultStateDir is used if it is empty
+	StateDir string
+	// CgroupRoot is the cgroup v2 directory the cgroups of services are created in, DefaultCgroupRoot is used if it is empty
+	CgroupRoot string
 }
 
 const (
@@ -25,23 +44,95 @@ const (
 	ServiceDefinitionDir = "/etc/sminit"
 )
 
+const (
+	// LogFormatConsole writes human readable sminit logs
+	LogFormatConsole = "console"
+	// LogFormatJSON writes sminit logs as json objects, one per line
+	LogFormatJSON = "json"
+)
+
 var (
 	// SminitLog is the default logger used in sminit
-	SminitLog = log.Output(zerolog.ConsoleWriter{
+	SminitLog = log.Output(newConsoleWriter())
+
+	// logConfig is the configuration SminitLog was created with, it is used to create loggers for services
+	logConfig = logOptions{
+		format: LogFormatConsole,
+		level:  zerolog.TraceLevel,
+	}
+)
+
+type logOptions struct {
+	format string
+	level  zerolog.Level
+	// syslog is true if sminit logs are copied to the local syslog
+	syslog bool
+}
+
+// newConsoleWriter returns a writer of human readable logs, that shows the service of service log lines before the message
+func newConsoleWriter() zerolog.ConsoleWriter {
+	return zerolog.ConsoleWriter{
 		Out: os.Stdout,
 		FieldsExclude: []string{
-			"component",
+			"service",
+			"stream",
+			"pid",
 		},
 		PartsOrder: []string{
 			"level",
-			"component",
+			"service",
 			"message",
 		},
-	}).With().Str("component", "sminit:").Logger()
-)
+	}
+}
+
+// ConfigureLog sets the format and minimum level of SminitLog. format should be console or json.
+// if toSyslog is true, sminit logs are also copied to the local syslog.
+func ConfigureLog(format string, level string, toSyslog bool) error {
+	lvl, err := zerolog.ParseLevel(level)
+	if err != nil {
+		return errors.Wrapf(err, "invalid log level %s", level)
+	}
+
+	if format != LogFormatConsole && format != LogFormatJSON {
+		return errors.Errorf("invalid log format %s, should be one of console, json", format)
+	}
+
+	logConfig = logOptions{
+		format: format,
+		level:  lvl,
+		syslog: toSyslog,
+	}
+
+	logger, err := newLogger("sminit")
+	if err != nil {
+		return err
+	}
+	SminitLog = logger
+
+	return nil
+}
+
+// newLogger creates a logger using logConfig. if sminit logs are copied to syslog, they are sent with the given tag.
+func newLogger(tag string) (zerolog.Logger, error) {
+	var out io.Writer = os.Stdout
+	if logConfig.format == LogFormatConsole {
+		out = newConsoleWriter()
+	}
+
+	if logConfig.syslog {
+		w, err := dialSyslog(syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+		if err != nil {
+			return zerolog.Logger{}, errors.Wrap(err, "failed to connect to syslog")
+		}
+		out = zerolog.MultiLevelWriter(out, zerolog.SyslogLevelWriter(w))
+	}
+
+	return zerolog.New(out).With().Timestamp().Logger().Level(logConfig.level), nil
+}
 
 // StartApp starts a daemon process responsible for tracking services, and exposing an http server that accepts requests to manipulate those services
-func StartApp() error {
+func StartApp(opts AppOptions) error {
 	sigs := make(chan os.Signal, 1)
 
 	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
@@ -52,30 +143,86 @@ func StartApp() error {
 		os.Exit(0)
 	}()
 
-	err := createFilesAndDirs()
-	if err != nil {
-		return errors.Wrap(err, "failed to create required files and directories")
+	// an instance executed by a running sminit instance takes over its files, listeners and services
+	var handover *reexecState
+	var listener, httpListener net.Listener
+	if IsReexec() {
+		var err error
+		handover, err = readReexecState()
+		if err != nil {
+			return err
+		}
+
+		listener, err = fileListener(handover.SocketListener, "socket")
+		if err != nil {
+			return err
+		}
+		httpListener, err = fileListener(handover.HTTPListener, "http")
+		if err != nil {
+			return err
+		}
+	} else {
+		err := createFilesAndDirs()
+		if err != nil {
+			return errors.Wrap(err, "failed to create required files and directories")
+		}
+
+		listener, err = net.Listen("unix", SminitSocketPath)
+		if err != nil {
+			return errors.Wrapf(err, "failed to create a listener on socket %s", SminitSocketPath)
+		}
+		httpListener, err = net.Listen("tcp", fmt.Sprintf("%s:%d", Address, Port))
+		if err != nil {
+			return errors.Wrapf(err, "failed to create a listener on %s:%d", Address, Port)
+		}
 	}
 
-	listener, err := net.Listen("unix", SminitSocketPath)
+	services, err := LoadAll(ServiceDefinitionDir)
 	if err != nil {
-		return errors.Wrapf(err, "failed to create a listener on socket %s", SminitSocketPath)
+		return err
 	}
 
-	services, err := LoadAll(ServiceDefinitionDir)
+	stateDir := opts.StateDir
+	if stateDir == "" {
+		stateDir = DefaultStateDir
+	}
+	state, err := loadState(stateDir)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "failed to load state")
 	}
+	state.restoreServices(services)
 
 	manager, err := NewManager(services)
 	if err != nil {
 		return err
 	}
+
+	journal, err := openJournal(stateDir)
+	if err != nil {
+		return errors.Wrap(err, "failed to open journal")
+	}
+	defer journal.close()
+	manager.setJournal(journal)
+	manager.setState(state)
+
+	cgroupRoot := opts.CgroupRoot
+	if cgroupRoot == "" {
+		cgroupRoot = DefaultCgroupRoot
+	}
+	manager.setCgroupRoot(cgroupRoot)
+	if handover != nil {
+		manager.adoptServices(handover.Services)
+	}
+
 	manager.fireServices()
 
 	watcher := App{
-		Manager:  manager,
-		Listener: listener,
+		Manager:      manager,
+		Listener:     listener,
+		httpListener: httpListener,
+	}
+	if opts.Metrics {
+		watcher.apiMetrics = newAPIMetrics()
 	}
 
 	err = watcher.startHTTPServer()
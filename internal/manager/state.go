@@ -0,0 +1,259 @@
+package manager
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// stateFileName is the name of the file the intent of the user is kept in, in the state directory
+const stateFileName = "state.yaml"
+
+// userState is what the user asked sminit to do at runtime, that should survive sminit restarts
+type userState struct {
+	// Stopped are services stopped by the user, they are not started when sminit starts until they are started explicitly
+	Stopped map[string]bool `yaml:"stopped,omitempty"`
+	// Added are the definitions of services added while sminit was running
+	Added map[string]ServiceOptions `yaml:"added,omitempty"`
+	// Disabled overrides the disabled field of service definitions, for services enabled or disabled by the user
+	Disabled map[string]bool `yaml:"disabled,omitempty"`
+	// LastRuns are the times of the last runs of scheduled services, used to catch up with runs missed while sminit was not running
+	LastRuns map[string]time.Time `yaml:"last_runs,omitempty"`
+}
+
+// stateStore keeps userState in a file, every change is written immediately
+type stateStore struct {
+	path  string
+	state userState
+	mut   sync.Mutex
+}
+
+// loadState reads the state file in the given state directory, an empty state is returned if there is no state file
+func loadState(stateDir string) (*stateStore, error) {
+	err := os.MkdirAll(stateDir, 0750)
+	if err != nil {
+		return nil, errors.Wrapf(err, "could not create state directory %s", stateDir)
+	}
+
+	store := &stateStore{
+		path: path.Join(stateDir, stateFileName),
+	}
+
+	content, err := os.ReadFile(store.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		store.state = userState{}
+	} else if err != nil {
+		return nil, errors.Wrapf(err, "could not read state file %s", store.path)
+	} else if err := yaml.Unmarshal(content, &store.state); err != nil {
+		return nil, errors.Wrapf(err, "could not unmarshal state file %s", store.path)
+	}
+
+	if store.state.Stopped == nil {
+		store.state.Stopped = map[string]bool{}
+	}
+	if store.state.Added == nil {
+		store.state.Added = map[string]ServiceOptions{}
+	}
+	if store.state.Disabled == nil {
+		store.state.Disabled = map[string]bool{}
+	}
+	if store.state.LastRuns == nil {
+		store.state.LastRuns = map[string]time.Time{}
+	}
+	for name, opts := range store.state.Added {
+		opts.Name = name
+		store.state.Added[name] = opts
+	}
+
+	return store, nil
+}
+
+// update applies fn to the state, then writes it to the state file. it does nothing if the store is nil.
+func (s *stateStore) update(fn func(state *userState)) {
+	if s == nil {
+		return
+	}
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	fn(&s.state)
+	if err := s.save(); err != nil {
+		SminitLog.Error().Msgf("failed to save state. %s", err.Error())
+	}
+}
+
+// save writes the state to a temporary file, then renames it to the state file, so the state file is never partially written
+func (s *stateStore) save() error {
+	content, err := yaml.Marshal(s.state)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal state")
+	}
+
+	tmpPath := s.path + ".tmp"
+	err = os.WriteFile(tmpPath, content, 0640)
+	if err != nil {
+		return errors.Wrapf(err, "could not write %s", tmpPath)
+	}
+
+	err = os.Rename(tmpPath, s.path)
+	if err != nil {
+		return errors.Wrapf(err, "could not rename %s to %s", tmpPath, s.path)
+	}
+	return nil
+}
+
+func (s *stateStore) setStopped(name string, stopped bool) {
+	s.update(func(state *userState) {
+		if stopped {
+			state.Stopped[name] = true
+		} else {
+			delete(state.Stopped, name)
+		}
+	})
+}
+
+func (s *stateStore) setAdded(opts ServiceOptions) {
+	s.update(func(state *userState) {
+		state.Added[opts.Name] = opts
+	})
+}
+
+// setDisabled records that the user has disabled or enabled the service. the record is dropped if it matches the definition of the service.
+func (s *stateStore) setDisabled(name string, disabled bool, definition bool) {
+	s.update(func(state *userState) {
+		if disabled == definition {
+			delete(state.Disabled, name)
+		} else {
+			state.Disabled[name] = disabled
+		}
+	})
+}
+
+func (s *stateStore) setLastRun(name string, t time.Time) {
+	s.update(func(state *userState) {
+		state.LastRuns[name] = t
+	})
+}
+
+// forget drops everything kept about the service with the given name
+func (s *stateStore) forget(name string) {
+	s.update(func(state *userState) {
+		delete(state.Stopped, name)
+		delete(state.Added, name)
+		delete(state.Disabled, name)
+		delete(state.LastRuns, name)
+	})
+}
+
+// restoreServices adds services added at runtime to the given services. definitions in the services directory take precedence.
+// added services that depend on a service that is no longer defined, or that are part of a dependency cycle, are dropped and forgotten.
+func (s *stateStore) restoreServices(services map[string]ServiceOptions) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	restored := map[string]bool{}
+	for name, opts := range s.state.Added {
+		if _, ok := services[name]; !ok {
+			services[name] = opts
+			restored[name] = true
+		}
+	}
+
+	invalid := map[string]string{}
+	// dropping a service could leave services depending on it without a parent, so services are checked until none is dropped
+	for dropped := true; dropped; {
+		dropped = false
+		for name := range restored {
+			reason := ""
+			for _, parent := range services[name].After {
+				if _, ok := services[parent]; !ok {
+					reason = fmt.Sprintf("service %s it runs after does not exist", parent)
+					break
+				}
+			}
+			if reason == "" && dependsOn(services, name, name) {
+				reason = "it is part of a dependency cycle"
+			}
+			if reason != "" {
+				invalid[name] = reason
+				delete(services, name)
+				delete(restored, name)
+				dropped = true
+			}
+		}
+	}
+
+	if len(invalid) == 0 {
+		return
+	}
+	for name, reason := range invalid {
+		SminitLog.Error().Msgf("dropping service %s added at runtime, %s", name, reason)
+		delete(s.state.Stopped, name)
+		delete(s.state.Added, name)
+		delete(s.state.Disabled, name)
+		delete(s.state.LastRuns, name)
+	}
+	if err := s.save(); err != nil {
+		SminitLog.Error().Msgf("failed to save state. %s", err.Error())
+	}
+}
+
+// dependsOn returns true if the service with the given name runs after target, directly or through other services
+func dependsOn(services map[string]ServiceOptions, name, target string) bool {
+	visited := map[string]bool{}
+	stack := append([]string{}, services[name].After...)
+	for len(stack) > 0 {
+		parent := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if parent == target {
+			return true
+		}
+		if visited[parent] {
+			continue
+		}
+		visited[parent] = true
+		stack = append(stack, services[parent].After...)
+	}
+	return false
+}
+
+// setState makes the manager keep the intent of the user in the given store, marks services stopped by the user as stopped,
+// enables or disables services as the user did, and restores the last runs of scheduled services.
+// it should be called before services are fired.
+func (m *Manager) setState(store *stateStore) {
+	m.state = store
+
+	store.mut.Lock()
+	defer store.mut.Unlock()
+
+	for name := range store.state.Stopped {
+		if service, ok := m.getService(name); ok {
+			service.changeStatus(Stopped)
+		}
+	}
+
+	for name, disabled := range store.state.Disabled {
+		service, ok := m.getService(name)
+		if !ok {
+			continue
+		}
+		if disabled {
+			service.changeStatus(Disabled)
+		} else if service.isDisabled() {
+			service.changeStatus(Pending)
+		}
+	}
+
+	for name, lastRun := range store.state.LastRuns {
+		if service, ok := m.getService(name); ok {
+			service.setLastRun(lastRun)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package manager
+
+import (
+	"fmt"
+	"log/syslog"
+	"strings"
+)
+
+var (
+	// SyslogNetwork and SyslogAddress are used to connect to syslog, the local syslog (/dev/log) is used if they are empty
+	SyslogNetwork = ""
+	SyslogAddress = ""
+)
+
+// LogSyslog sends service output to syslog
+const LogSyslog = "syslog"
+
+// SyslogOptions configures how the output of a service is sent to syslog
+type SyslogOptions struct {
+	// Facility is a syslog facility like daemon or local0, the default is daemon
+	Facility string `yaml:"facility,omitempty"`
+	// Tag is the tag of the messages, the default is the service name
+	Tag string `yaml:"tag,omitempty"`
+	// StdoutPriority is the priority of stdout lines, the default is info
+	StdoutPriority string `yaml:"stdout_priority,omitempty"`
+	// StderrPriority is the priority of stderr lines, the default is err
+	StderrPriority string `yaml:"stderr_priority,omitempty"`
+}
+
+var syslogFacilities = map[string]syslog.Priority{
+	"kern":     syslog.LOG_KERN,
+	"user":     syslog.LOG_USER,
+	"mail":     syslog.LOG_MAIL,
+	"daemon":   syslog.LOG_DAEMON,
+	"auth":     syslog.LOG_AUTH,
+	"syslog":   syslog.LOG_SYSLOG,
+	"lpr":      syslog.LOG_LPR,
+	"news":     syslog.LOG_NEWS,
+	"uucp":     syslog.LOG_UUCP,
+	"cron":     syslog.LOG_CRON,
+	"authpriv": syslog.LOG_AUTHPRIV,
+	"ftp":      syslog.LOG_FTP,
+	"local0":   syslog.LOG_LOCAL0,
+	"local1":   syslog.LOG_LOCAL1,
+	"local2":   syslog.LOG_LOCAL2,
+	"local3":   syslog.LOG_LOCAL3,
+	"local4":   syslog.LOG_LOCAL4,
+	"local5":   syslog.LOG_LOCAL5,
+	"local6":   syslog.LOG_LOCAL6,
+	"local7":   syslog.LOG_LOCAL7,
+}
+
+var syslogSeverities = map[string]syslog.Priority{
+	"emerg":   syslog.LOG_EMERG,
+	"alert":   syslog.LOG_ALERT,
+	"crit":    syslog.LOG_CRIT,
+	"err":     syslog.LOG_ERR,
+	"error":   syslog.LOG_ERR,
+	"warning": syslog.LOG_WARNING,
+	"warn":    syslog.LOG_WARNING,
+	"notice":  syslog.LOG_NOTICE,
+	"info":    syslog.LOG_INFO,
+	"debug":   syslog.LOG_DEBUG,
+}
+
+// syslogSink sends lines of a service to syslog, with a priority depending on their stream
+type syslogSink struct {
+	writer         *syslog.Writer
+	stdoutPriority syslog.Priority
+	stderrPriority syslog.Priority
+}
+
+func dialSyslog(priority syslog.Priority, tag string) (*syslog.Writer, error) {
+	return syslog.Dial(SyslogNetwork, SyslogAddress, priority, tag)
+}
+
+func parseSyslogPriority(priorities map[string]syslog.Priority, value string, defaultValue syslog.Priority) (syslog.Priority, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	priority, ok := priorities[strings.ToLower(value)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog value %s", value)
+	}
+	return priority, nil
+}
+
+// validate checks that facility and priorities of opts are known
+func (opts SyslogOptions) validate() error {
+	_, _, _, err := opts.priorities()
+	return err
+}
+
+func (opts SyslogOptions) priorities() (facility, stdout, stderr syslog.Priority, err error) {
+	facility, err = parseSyslogPriority(syslogFacilities, opts.Facility, syslog.LOG_DAEMON)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	stdout, err = parseSyslogPriority(syslogSeverities, opts.StdoutPriority, syslog.LOG_INFO)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	stderr, err = parseSyslogPriority(syslogSeverities, opts.StderrPriority, syslog.LOG_ERR)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	return facility, stdout, stderr, nil
+}
+
+// newSyslogSink connects to syslog using the syslog options of a service
+func newSyslogSink(serviceName string, opts SyslogOptions) (*syslogSink, error) {
+	facility, stdout, stderr, err := opts.priorities()
+	if err != nil {
+		return nil, err
+	}
+
+	tag := opts.Tag
+	if tag == "" {
+		tag = serviceName
+	}
+
+	writer, err := dialSyslog(facility|stdout, tag)
+	if err != nil {
+		return nil, err
+	}
+
+	return &syslogSink{
+		writer:         writer,
+		stdoutPriority: stdout,
+		stderrPriority: stderr,
+	}, nil
+}
+
+func (s *syslogSink) write(stream, line string) {
+	priority := s.stdoutPriority
+	if stream == StreamStderr {
+		priority = s.stderrPriority
+	}
+
+	var err error
+	switch priority {
+	case syslog.LOG_EMERG:
+		err = s.writer.Emerg(line)
+	case syslog.LOG_ALERT:
+		err = s.writer.Alert(line)
+	case syslog.LOG_CRIT:
+		err = s.writer.Crit(line)
+	case syslog.LOG_ERR:
+		err = s.writer.Err(line)
+	case syslog.LOG_WARNING:
+		err = s.writer.Warning(line)
+	case syslog.LOG_NOTICE:
+		err = s.writer.Notice(line)
+	case syslog.LOG_DEBUG:
+		err = s.writer.Debug(line)
+	default:
+		err = s.writer.Info(line)
+	}
+	if err != nil {
+		SminitLog.Error().Msgf("failed to send line to syslog. %s", err.Error())
+	}
+}
+
+func (s *syslogSink) close() {
+	_ = s.writer.Close()
+}
@@ -0,0 +1,50 @@
+package manager
+
+import (
+	"net"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSyslog(t *testing.T) {
+	address := path.Join(t.TempDir(), "log.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: address, Net: "unixgram"})
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	SyslogNetwork, SyslogAddress = "unixgram", address
+	defer func() {
+		SyslogNetwork, SyslogAddress = "", ""
+	}()
+
+	t.Run("invalid_options", func(t *testing.T) {
+		assert.Error(t, SyslogOptions{Facility: "nope"}.validate())
+		assert.Error(t, SyslogOptions{StderrPriority: "loud"}.validate())
+		assert.NoError(t, SyslogOptions{Facility: "local3", StdoutPriority: "notice"}.validate())
+	})
+
+	t.Run("sink", func(t *testing.T) {
+		sink, err := newSyslogSink("s1", SyslogOptions{Facility: "local0"})
+		assert.NoError(t, err)
+		defer sink.close()
+
+		buf := make([]byte, 1024)
+
+		sink.write(StreamStdout, "hello")
+		n, err := conn.Read(buf)
+		assert.NoError(t, err)
+		// local0 (16) * 8 + info (6)
+		assert.Contains(t, string(buf[:n]), "<134>")
+		assert.Contains(t, string(buf[:n]), "s1[")
+		assert.Contains(t, string(buf[:n]), "hello")
+
+		sink.write(StreamStderr, "oops")
+		n, err = conn.Read(buf)
+		assert.NoError(t, err)
+		// local0 (16) * 8 + err (3)
+		assert.Contains(t, string(buf[:n]), "<131>")
+		assert.Contains(t, string(buf[:n]), "oops")
+	})
+}
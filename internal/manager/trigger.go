@@ -0,0 +1,107 @@
+package manager
+
+import (
+	"fmt"
+)
+
+const (
+	triggerFailure = "failure"
+	triggerSuccess = "success"
+)
+
+// failService marks the service as failed with the given reason, and starts its on_failure services if it was not failed already
+func (m *Manager) failService(service *Service, reason Reason) {
+	if service.fail(reason) {
+		m.trigger(service, triggerFailure, service.options.OnFailure)
+	}
+}
+
+// succeedService marks the service as successful, and starts its on_success services if it was not successful already
+func (m *Manager) succeedService(service *Service) {
+	if service.changeStatus(Successful) {
+		m.trigger(service, triggerSuccess, service.options.OnSuccess)
+	}
+}
+
+// trigger starts the given services because the service has failed or succeeded, with the result of the service in their environment.
+// services are started in the background, since the service could be being stopped by a user holding the ops lock.
+func (m *Manager) trigger(service *Service, result string, targets []string) {
+	if len(targets) == 0 {
+		return
+	}
+
+	env := service.resultEnv(result)
+	go func() {
+		m.ops.Lock()
+		defer m.ops.Unlock()
+
+		for _, name := range targets {
+			m.triggerService(name, service.Name, env)
+		}
+	}()
+}
+
+// triggerService starts the service with the given name with env added to its environment, unless it is running, stopped or disabled
+func (m *Manager) triggerService(name string, by string, env []string) {
+	target, ok := m.getService(name)
+	if !ok {
+		SminitLog.Error().Msgf("service %s should be started by service %s, but it is not tracked", name, by)
+		return
+	}
+
+	status := target.desc().Status
+	switch status {
+	case Started, Running, Stopped, Disabled:
+		target.logger().Info().Msgf("service %s is not started by service %s, it is %s", name, by, status)
+		return
+	}
+
+	target.logger().Info().Msgf("service %s is started by service %s", name, by)
+	target.setTriggerEnv(env)
+	// a triggered run of a scheduled service is a run like any other
+	target.setRunDue()
+	target.changeStatus(Pending)
+
+	if m.isEligibleToRun(name) {
+		target.startSignal <- true
+	}
+}
+
+// resultEnv describes the result of the service as environment variables, for the services it triggers
+func (s *Service) resultEnv(result string) []string {
+	desc := s.desc()
+
+	env := []string{
+		fmt.Sprintf("SMINIT_TRIGGER=%s", result),
+		fmt.Sprintf("SMINIT_TRIGGER_SERVICE=%s", s.Name),
+	}
+	if result == triggerFailure {
+		env = append(env, fmt.Sprintf("SMINIT_TRIGGER_REASON=%s", desc.FailureReason))
+	}
+
+	// a service that failed before its process was started has no exit of its own
+	started := desc.FailureReason != ReasonStartError && desc.FailureReason != ReasonStartPreFailed
+	if desc.LastExit != nil && (result == triggerSuccess || started) {
+		env = append(env, fmt.Sprintf("SMINIT_TRIGGER_EXIT_CODE=%d", desc.LastExit.Code))
+		if desc.LastExit.Signal != "" {
+			env = append(env, fmt.Sprintf("SMINIT_TRIGGER_EXIT_SIGNAL=%s", desc.LastExit.Signal))
+		}
+	}
+	return env
+}
+
+func (s *Service) setTriggerEnv(env []string) {
+	s.mut.Lock()
+	s.triggerEnv = env
+	s.mut.Unlock()
+}
+
+// takeTriggerEnv returns the environment added by the service that triggered this one, if any, and clears it so it is only used by one run
+func (s *Service) takeTriggerEnv() []string {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	env := s.triggerEnv
+	s.triggerEnv = nil
+	return env
+}
@@ -0,0 +1,185 @@
+package manager
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ioprioWhoProcess makes ioprio_set apply to a single process
+	ioprioWhoProcess = 1
+	// ioprioClassShift is the position of the class in an io priority
+	ioprioClassShift = 13
+	// maxCPUs is the number of cpus a cpu affinity could be set for
+	maxCPUs = len(unix.CPUSet{}) * 64
+)
+
+// ioprioClasses maps io scheduling class names to their values
+var ioprioClasses = map[string]int{
+	"realtime":    1,
+	"best-effort": 2,
+	"idle":        3,
+}
+
+// RlimitOptions are resource limits applied to the process of a service.
+// each limit is either a single value used as soft and hard limit like 65536, "soft:hard" like 1024:65536, or unlimited.
+type RlimitOptions struct {
+	// Nofile is the maximum number of open files
+	Nofile string `yaml:"nofile,omitempty"`
+	// Nproc is the maximum number of processes of the user of the service
+	Nproc string `yaml:"nproc,omitempty"`
+	// Core is the maximum size of core dumps, like 0 or 1G
+	Core string `yaml:"core,omitempty"`
+	// Memlock is the maximum size of memory locked in ram, like 64M
+	Memlock string `yaml:"memlock,omitempty"`
+}
+
+// limits returns the set rlimits keyed by resource
+func (r RlimitOptions) limits() map[int]string {
+	limits := map[int]string{}
+	for resource, value := range map[int]string{
+		unix.RLIMIT_NOFILE:  r.Nofile,
+		unix.RLIMIT_NPROC:   r.Nproc,
+		unix.RLIMIT_CORE:    r.Core,
+		unix.RLIMIT_MEMLOCK: r.Memlock,
+	} {
+		if value != "" {
+			limits[resource] = value
+		}
+	}
+	return limits
+}
+
+func (r RlimitOptions) validate() error {
+	for _, value := range r.limits() {
+		if _, err := parseRlimit(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseRlimit parses a limit like 65536, 1024:65536, 64M or unlimited
+func parseRlimit(value string) (unix.Rlimit, error) {
+	parts := strings.SplitN(value, ":", 2)
+	soft, err := parseRlimitValue(parts[0])
+	if err != nil {
+		return unix.Rlimit{}, errors.Wrapf(err, "invalid rlimit %s", value)
+	}
+	hard := soft
+	if len(parts) == 2 {
+		if hard, err = parseRlimitValue(parts[1]); err != nil {
+			return unix.Rlimit{}, errors.Wrapf(err, "invalid rlimit %s", value)
+		}
+	}
+	if soft > hard {
+		return unix.Rlimit{}, errors.Errorf("invalid rlimit %s, soft limit should not be greater than hard limit", value)
+	}
+	return unix.Rlimit{Cur: soft, Max: hard}, nil
+}
+
+func parseRlimitValue(value string) (uint64, error) {
+	value = strings.TrimSpace(value)
+	if value == "unlimited" || value == "infinity" {
+		return unix.RLIM_INFINITY, nil
+	}
+	size, err := ParseSize(value)
+	if err != nil {
+		return 0, err
+	}
+	return uint64(size), nil
+}
+
+// parseIOPrio parses an io priority like best-effort:4, realtime:0 or idle
+func parseIOPrio(value string) (int, error) {
+	parts := strings.SplitN(value, ":", 2)
+	class, ok := ioprioClasses[parts[0]]
+	if !ok {
+		return 0, errors.Errorf("invalid ioprio %s, class should be one of realtime, best-effort, idle", value)
+	}
+
+	level := 0
+	if len(parts) == 2 {
+		l, err := strconv.Atoi(parts[1])
+		if err != nil || l < 0 || l > 7 {
+			return 0, errors.Errorf("invalid ioprio %s, level should be between 0 and 7", value)
+		}
+		level = l
+	}
+	return class<<ioprioClassShift | level, nil
+}
+
+// validateTunables checks the per-process tunables of the service definition
+func (s ServiceOptions) validateTunables() error {
+	if err := s.Rlimits.validate(); err != nil {
+		return err
+	}
+	if s.Nice < -20 || s.Nice > 19 {
+		return errors.Errorf("nice %d should be between -20 and 19", s.Nice)
+	}
+	if s.OOMScoreAdj < -1000 || s.OOMScoreAdj > 1000 {
+		return errors.Errorf("oom_score_adj %d should be between -1000 and 1000", s.OOMScoreAdj)
+	}
+	for _, cpu := range s.CPUAffinity {
+		if cpu < 0 || cpu >= maxCPUs {
+			return errors.Errorf("cpu_affinity cpu %d should be between 0 and %d", cpu, maxCPUs-1)
+		}
+	}
+	if s.IOPrio != "" {
+		if _, err := parseIOPrio(s.IOPrio); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyTunables applies the rlimits, nice value, oom score adjustment, cpu affinity and io priority in opts to the calling thread and its process.
+// nice value, cpu affinity and io priority are attributes of threads, so the command should be executed from the calling thread.
+func applyTunables(opts execOptions) error {
+	for resource, value := range opts.Rlimits.limits() {
+		// values are validated when the service is loaded
+		limit, _ := parseRlimit(value)
+		// syscall.Setrlimit is used so the go runtime does not restore its own open files limit when the command is executed
+		if err := syscall.Setrlimit(resource, &syscall.Rlimit{Cur: limit.Cur, Max: limit.Max}); err != nil {
+			return errors.Wrapf(err, "could not set rlimit %s", value)
+		}
+	}
+
+	if opts.Nice != 0 {
+		if err := unix.Setpriority(unix.PRIO_PROCESS, 0, opts.Nice); err != nil {
+			return errors.Wrapf(err, "could not set nice to %d", opts.Nice)
+		}
+	}
+
+	if opts.OOMScoreAdj != 0 {
+		err := os.WriteFile("/proc/self/oom_score_adj", []byte(strconv.Itoa(opts.OOMScoreAdj)), 0644)
+		if err != nil {
+			return errors.Wrapf(err, "could not set oom_score_adj to %d", opts.OOMScoreAdj)
+		}
+	}
+
+	if len(opts.CPUAffinity) > 0 {
+		set := unix.CPUSet{}
+		for _, cpu := range opts.CPUAffinity {
+			set.Set(cpu)
+		}
+		if err := unix.SchedSetaffinity(0, &set); err != nil {
+			return errors.Wrapf(err, "could not set cpu_affinity to %v", opts.CPUAffinity)
+		}
+	}
+
+	if opts.IOPrio != "" {
+		prio, _ := parseIOPrio(opts.IOPrio)
+		_, _, errno := unix.Syscall(unix.SYS_IOPRIO_SET, ioprioWhoProcess, 0, uintptr(prio))
+		if errno != 0 {
+			return errors.Wrapf(errno, "could not set ioprio to %s", opts.IOPrio)
+		}
+	}
+
+	return nil
+}
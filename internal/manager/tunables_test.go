@@ -0,0 +1,69 @@
+package manager
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/sys/unix"
+)
+
+func TestTunables(t *testing.T) {
+	t.Run("rlimits", func(t *testing.T) {
+		tests := map[string]unix.Rlimit{
+			"65536":          {Cur: 65536, Max: 65536},
+			"1024:65536":     {Cur: 1024, Max: 65536},
+			"64M":            {Cur: 64 << 20, Max: 64 << 20},
+			"unlimited":      {Cur: unix.RLIM_INFINITY, Max: unix.RLIM_INFINITY},
+			"0:infinity":     {Cur: 0, Max: unix.RLIM_INFINITY},
+			"1024:unlimited": {Cur: 1024, Max: unix.RLIM_INFINITY},
+		}
+		for value, want := range tests {
+			got, err := parseRlimit(value)
+			assert.NoError(t, err, value)
+			assert.Equal(t, want, got, value)
+		}
+
+		for _, value := range []string{"", "many", "-1", "65536:1024", "unlimited:1024", "1:2:3"} {
+			_, err := parseRlimit(value)
+			assert.Error(t, err, value)
+		}
+	})
+
+	t.Run("ioprio", func(t *testing.T) {
+		tests := map[string]int{
+			"realtime:0":    1 << 13,
+			"best-effort:4": 2<<13 | 4,
+			"best-effort":   2 << 13,
+			"idle":          3 << 13,
+		}
+		for value, want := range tests {
+			got, err := parseIOPrio(value)
+			assert.NoError(t, err, value)
+			assert.Equal(t, want, got, value)
+		}
+
+		for _, value := range []string{"", "fast", "best-effort:8", "realtime:-1", "idle:x"} {
+			_, err := parseIOPrio(value)
+			assert.Error(t, err, value)
+		}
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		assert.NoError(t, ServiceOptions{}.validateTunables())
+		assert.NoError(t, ServiceOptions{
+			Rlimits:     RlimitOptions{Nofile: "1024:65536", Core: "0"},
+			Nice:        -5,
+			OOMScoreAdj: -500,
+			CPUAffinity: []int{0, 1},
+			IOPrio:      "best-effort:2",
+		}.validateTunables())
+
+		assert.Error(t, ServiceOptions{Rlimits: RlimitOptions{Nproc: "lots"}}.validateTunables())
+		assert.Error(t, ServiceOptions{Nice: 20}.validateTunables())
+		assert.Error(t, ServiceOptions{Nice: -21}.validateTunables())
+		assert.Error(t, ServiceOptions{OOMScoreAdj: 1001}.validateTunables())
+		assert.Error(t, ServiceOptions{CPUAffinity: []int{-1}}.validateTunables())
+		assert.Error(t, ServiceOptions{CPUAffinity: []int{maxCPUs}}.validateTunables())
+		assert.Error(t, ServiceOptions{IOPrio: "urgent"}.validateTunables())
+	})
+}